@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Built-in audit policy levels, see https://kubernetes.io/docs/tasks/debug/debug-cluster/audit/#audit-policy.
+const (
+	AuditLevelMetadata        = "Metadata"
+	AuditLevelRequest         = "Request"
+	AuditLevelRequestResponse = "RequestResponse"
+)
+
+var AuditLevels = []string{AuditLevelMetadata, AuditLevelRequest, AuditLevelRequestResponse}
+
+// Audit turns on API server auditing with a built-in or custom policy.
+type Audit struct {
+	// Level selects a built-in policy that logs every request at that level. It defaults to Metadata.
+	Level string `yaml:"level,omitempty" json:"level,omitempty"`
+	// Policy is a custom audit.k8s.io Policy, used instead of Level.
+	Policy string `yaml:"policy,omitempty" json:"policy,omitempty"`
+
+	// LogPath defaults to server/logs/audit.log in the data-dir.
+	LogPath   string `yaml:"logPath,omitempty" json:"logPath,omitempty"`
+	MaxAge    *int   `yaml:"maxAge,omitempty" json:"maxAge,omitempty"`
+	MaxBackup *int   `yaml:"maxBackup,omitempty" json:"maxBackup,omitempty"`
+	MaxSize   *int   `yaml:"maxSize,omitempty" json:"maxSize,omitempty"`
+}
+
+// ValidateAudit checks that a single policy is given and that it is an audit Policy.
+func ValidateAudit(a *Audit) error {
+	if a == nil {
+		return nil
+	}
+	if a.Level != "" && a.Policy != "" {
+		return fmt.Errorf("audit: level and policy are mutually exclusive")
+	}
+	if a.Level != "" && !slices.Contains(AuditLevels, a.Level) {
+		return fmt.Errorf("audit: invalid level %q, expected one of %s", a.Level, strings.Join(AuditLevels, ", "))
+	}
+	if a.Policy != "" {
+		var policy struct {
+			APIVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+		}
+		if err := yaml.Unmarshal([]byte(a.Policy), &policy); err != nil {
+			return fmt.Errorf("audit: invalid policy: %w", err)
+		}
+		if policy.Kind != "Policy" || !strings.HasPrefix(policy.APIVersion, "audit.k8s.io/") {
+			return fmt.Errorf("audit: policy must be an audit.k8s.io Policy, got %s %s", policy.APIVersion, policy.Kind)
+		}
+	}
+	for name, v := range map[string]*int{"maxAge": a.MaxAge, "maxBackup": a.MaxBackup, "maxSize": a.MaxSize} {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("audit: %s must not be negative", name)
+		}
+	}
+	if strings.ContainsAny(a.LogPath, "\n\r\x00") {
+		return fmt.Errorf("audit: invalid logPath %q", a.LogPath)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ValidateAudit(t *testing.T) {
+	tests := []struct {
+		name    string
+		audit   *Audit
+		wantErr string
+	}{
+		{name: "No section"},
+		{name: "Level and rotation", audit: &Audit{Level: AuditLevelMetadata, MaxAge: intPtr(30)}},
+		{name: "Policy", audit: &Audit{Policy: "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n  - level: None\n"}},
+		{name: "Unknown level", audit: &Audit{Level: "Everything"}, wantErr: `audit: invalid level "Everything"`},
+		{name: "Level and policy", audit: &Audit{Level: AuditLevelMetadata, Policy: "apiVersion: audit.k8s.io/v1\nkind: Policy\n"}, wantErr: "audit: level and policy are mutually exclusive"},
+		{name: "Not a policy", audit: &Audit{Policy: "apiVersion: v1\nkind: ConfigMap\n"}, wantErr: "audit: policy must be an audit.k8s.io Policy, got v1 ConfigMap"},
+		{name: "Invalid policy", audit: &Audit{Policy: "rules: ["}, wantErr: "audit: invalid policy"},
+		{name: "Negative rotation", audit: &Audit{MaxAge: intPtr(-1)}, wantErr: "audit: maxAge must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAudit(tt.audit)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateAudit() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateAudit() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
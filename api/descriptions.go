@@ -0,0 +1,143 @@
+package api
+
+// Descriptions holds the k3s help text of every key in K3sServerConfig and K3sAgentConfig.
+var Descriptions = map[string]string{
+	"advertise-address":                 "IPv4/IPv6 address that apiserver uses to advertise to members of the cluster",
+	"advertise-port":                    "Port that apiserver uses to advertise to members of the cluster",
+	"agent-token":                       "Shared secret used to join agents to the cluster, but not servers",
+	"agent-token-file":                  "File containing the agent secret",
+	"airgap-extra-registry":             "Registry to use as an alias for images in airgap image tarballs",
+	"alsologtostderr":                   "Log to standard error as well as file (if set)",
+	"apiserver-bind-address":            "Address the apiserver binds to",
+	"apiserver-port":                    "Port the apiserver listens on",
+	"bind-address":                      "k3s bind address",
+	"cluster-cidr":                      "IPv4/IPv6 network CIDRs to use for pod IPs",
+	"cluster-dns":                       "IPv4 Cluster IP for coredns service. Should be in your service-cidr range",
+	"cluster-domain":                    "Cluster Domain",
+	"cluster-init":                      "Initialize a new cluster using embedded Etcd",
+	"cluster-reset":                     "Forget all peers and become sole member of a new cluster",
+	"cluster-reset-restore-path":        "Path to snapshot file to be restored",
+	"config":                            "Load configuration from file",
+	"container-runtime-endpoint":        "Disable embedded containerd and use the CRI socket at the given path",
+	"data-dir":                          "Folder to hold state",
+	"datastore-cafile":                  "TLS Certificate Authority file used to secure datastore backend communication",
+	"datastore-certfile":                "TLS certification file used to secure datastore backend communication",
+	"datastore-endpoint":                "Specify etcd, NATS, MySQL, Postgres, or SQLite data source name",
+	"datastore-keyfile":                 "TLS key file used to secure datastore backend communication",
+	"debug":                             "Turn on debug logs",
+	"default-local-storage-path":        "Default local storage path for local provisioner storage class",
+	"default-runtime":                   "Set the default runtime in containerd",
+	"disable":                           "Do not deploy packaged components and delete any deployed components",
+	"disable-agent":                     "Do not run a local agent and register a local kubelet",
+	"disable-apiserver":                 "Disable running api server",
+	"disable-apiserver-lb":              "Disable the agent's client-side load-balancer and connect directly to the configured server address",
+	"disable-cloud-controller":          "Disable k3s default cloud controller manager",
+	"disable-controller-manager":        "Disable running kube-controller-manager",
+	"disable-default-registry-endpoint": "Disables containerd's fallback default registry endpoint when a mirror is configured for that registry",
+	"disable-etcd":                      "Disable running etcd",
+	"disable-helm-controller":           "Disable Helm controller",
+	"disable-kube-proxy":                "Disable running kube-proxy",
+	"disable-network-policy":            "Disable k3s default network policy controller",
+	"disable-scheduler":                 "Disable Kubernetes default scheduler",
+	"docker":                            "Use cri-dockerd instead of containerd",
+	"egress-selector-mode":              "One of 'agent', 'cluster', 'pod', 'disabled'",
+	"embedded-registry":                 "Enable embedded distributed container registry",
+	"enable-pprof":                      "Enable pprof endpoint on supervisor port",
+	"etcd-arg":                          "Customized flag for etcd process",
+	"etcd-disable-snapshots":            "Disable automatic etcd snapshots",
+	"etcd-expose-metrics":               "Expose etcd metrics to client interface",
+	"etcd-s3":                           "Enable backup to S3",
+	"etcd-s3-access-key":                "S3 access key",
+	"etcd-s3-bucket":                    "S3 bucket name",
+	"etcd-s3-config-secret":             "Name of secret in the kube-system namespace used to configure S3, if etcd-s3 is enabled and no other etcd-s3 options are set",
+	"etcd-s3-endpoint":                  "S3 endpoint url",
+	"etcd-s3-endpoint-ca":               "S3 custom CA cert to connect to S3 endpoint",
+	"etcd-s3-folder":                    "S3 folder",
+	"etcd-s3-insecure":                  "Disables S3 over HTTPS",
+	"etcd-s3-proxy":                     "Proxy server to use when connecting to S3, overriding any proxy-releated environment variables",
+	"etcd-s3-region":                    "S3 region / bucket location",
+	"etcd-s3-secret-key":                "S3 secret key",
+	"etcd-s3-skip-ssl-verify":           "Disables S3 SSL certificate validation",
+	"etcd-s3-timeout":                   "S3 timeout",
+	"etcd-snapshot-compress":            "Compress etcd snapshot",
+	"etcd-snapshot-dir":                 "Directory to save db snapshots",
+	"etcd-snapshot-name":                "Set the base name of etcd snapshots",
+	"etcd-snapshot-retention":           "Number of snapshots to retain",
+	"etcd-snapshot-schedule-cron":       "Snapshot interval time in cron spec. eg. every 5 hours '0 */5 * * *'",
+	"flannel-backend":                   "Backend (valid values: 'none', 'vxlan', 'host-gw', 'wireguard-native')",
+	"flannel-cni-conf":                  "Override default flannel cni config file",
+	"flannel-conf":                      "Override default flannel config file",
+	"flannel-external-ip":               "Use node external IP addresses for Flannel traffic",
+	"flannel-iface":                     "Override default flannel interface",
+	"flannel-ipv6-masq":                 "Enable IPv6 masquerading for pod",
+	"helm-job-image":                    "Default image to use for helm jobs",
+	"https-listen-port":                 "HTTPS listen port",
+	"image-credential-provider-bin-dir": "The path to the directory where credential provider plugin binaries are located",
+	"image-credential-provider-config":  "The path to the credential provider plugin config file",
+	"image-service-endpoint":            "Disable embedded containerd image service and use remote image service socket at the given path",
+	"kine-tls":                          "Enable TLS on the kine etcd server socket",
+	"kube-apiserver-arg":                "Customized flag for kube-apiserver process",
+	"kube-cloud-controller-arg":         "Customized flag for kube-cloud-controller-manager process",
+	"kube-cloud-controller-manager-arg": "Customized flag for kube-cloud-controller-manager process",
+	"kube-controller-arg":               "Customized flag for kube-controller-manager process",
+	"kube-controller-manager-arg":       "Customized flag for kube-controller-manager process",
+	"kube-proxy-arg":                    "Customized flag for kube-proxy process",
+	"kube-scheduler-arg":                "Customized flag for kube-scheduler process",
+	"kubelet-arg":                       "Customized flag for kubelet process",
+	"lb-server-port":                    "Local port for supervisor client load-balancer",
+	"log":                               "Log to file",
+	"node-external-dns":                 "External DNS addresses to advertise for node",
+	"node-external-ip":                  "IPv4/IPv6 external IP addresses to advertise for node",
+	"node-internal-dns":                 "Internal DNS addresses to advertise for node",
+	"node-ip":                           "IPv4/IPv6 addresses to advertise for node",
+	"node-label":                        "Registering and starting kubelet with set of labels",
+	"node-name":                         "Node name",
+	"node-taint":                        "Registering kubelet with set of taints",
+	"nonroot-devices":                   "Allows non-root pods to access devices by setting device_ownership_from_security_context=true in the containerd CRI config",
+	"pause-image":                       "Customized pause image for containerd or docker sandbox",
+	"prefer-bundled-bin":                "Prefer bundled userspace binaries over host binaries",
+	"private-registry":                  "Private registry configuration file",
+	"protect-kernel-defaults":           "Kernel tuning behavior. If set, error if kernel tunables are different than kubelet defaults",
+	"resolv-conf":                       "Kubelet resolv.conf file",
+	"rootless":                          "Run rootless",
+	"secrets-encryption":                "Enable secret encryption at rest",
+	"selinux":                           "Enable SELinux in containerd",
+	"server":                            "Server to connect to",
+	"service-cidr":                      "IPv4/IPv6 network CIDRs to use for service IPs",
+	"service-node-port-range":           "Port range to reserve for services with NodePort visibility",
+	"servicelb-namespace":               "Namespace of the pods for the servicelb component",
+	"snapshotter":                       "Override default containerd snapshotter",
+	"supervisor-metrics":                "Expose k3s supervisor metrics on the supervisor port",
+	"supervisor-port":                   "Port the supervisor listens on",
+	"system-default-registry":           "Private registry to be used for all system images",
+	"tls-san":                           "Add additional hostnames or IPv4/IPv6 addresses as Subject Alternative Names on the server TLS cert",
+	"tls-san-security":                  "Protect the server TLS cert by refusing to add Subject Alternative Names not associated with the kubernetes apiserver service, server nodes, or values of the tls-san option",
+	"token":                             "Shared secret used to join a server or agent to a cluster",
+	"token-file":                        "File containing the token",
+	"v":                                 "Number for the log level verbosity",
+	"vmodule":                           "Comma-separated list of FILE_PATTERN=LOG_LEVEL settings for file-filtered logging",
+	"vpn-auth":                          "Credentials for the VPN provider. It must include the provider name and join key in the format name=<vpn-provider>,joinKey=<key>",
+	"vpn-auth-file":                     "File containing credentials for the VPN provider",
+	"with-node-id":                      "Append id to node name",
+	"write-kubeconfig":                  "Write kubeconfig for admin client to this file",
+	"write-kubeconfig-group":            "Write kubeconfig with this group",
+	"write-kubeconfig-mode":             "Write kubeconfig with this mode",
+}
+
+// ProviderDescriptions holds the help text of the provider-k3s sections in ProviderConfig.
+var ProviderDescriptions = map[string]string{
+	"registries":  "Mirrors, rewrites, auth and TLS settings rendered into /etc/rancher/k3s/registries.yaml",
+	"kernel":      "Kernel modules and sysctls added to the defaults for the flannel backend and kube-proxy mode",
+	"kubelet":     "Eviction, reserved resources, image GC, graceful shutdown and max pods, rendered into a KubeletConfiguration file",
+	"manifests":   "Raw manifests written to the k3s auto-deploy directory on server nodes",
+	"audit":       "API server audit policy and log rotation, rendered under the data-dir on server nodes",
+	"charts":      "HelmChart and HelmChartConfig resources written to the k3s auto-deploy directory on server nodes",
+	"encryption":  "Keys and provider of the EncryptionConfiguration for data at rest, rendered on server nodes",
+	"env":         "Environment variables written to /etc/default/k3s or /etc/default/k3s-agent for the k3s service",
+	"profile":     "Hardening profile layered under the cluster config on server nodes, such as cis",
+	"podSecurity": "PodSecurity admission defaults and exemptions, rendered into an AdmissionConfiguration on server nodes",
+	"oidc":        "OpenID Connect issuer, claims and CA for API server authentication on server nodes",
+	"preflight":   "Host checks run before k3s is started, reported to /run/provider-k3s/preflight.json",
+	"swap":        "Whether swap is disabled (default), left alone, or allowed for the kubelet",
+	"service":     "Limits, restart policy and dependencies of the k3s service, rendered into a systemd drop-in and an OpenRC settings file",
+}
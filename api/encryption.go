@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Encryption providers of the API server, see https://kubernetes.io/docs/tasks/administer-cluster/encrypt-data/.
+const (
+	EncryptionAESCBC    = "aescbc"
+	EncryptionSecretbox = "secretbox"
+)
+
+var EncryptionProviders = []string{EncryptionAESCBC, EncryptionSecretbox}
+
+// Encryption encrypts resources at rest with keys supplied at provisioning, instead of the keys k3s manages with
+// secrets-encryption.
+type Encryption struct {
+	// Provider defaults to aescbc.
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+	// Keys are tried in order when reading; the first one encrypts new writes unless Decrypt is set.
+	Keys []EncryptionKey `yaml:"keys" json:"keys"`
+	// Decrypt puts identity first, so new writes are stored unencrypted and the keys only read what they encrypted.
+	// It is the first step of turning encryption off.
+	Decrypt bool `yaml:"decrypt,omitempty" json:"decrypt,omitempty"`
+	// Resources defaults to secrets.
+	Resources []string `yaml:"resources,omitempty" json:"resources,omitempty"`
+	// RetiredKeys names keys of the current config that may be dropped, once every resource was rewritten with a
+	// newer key.
+	RetiredKeys []string `yaml:"retiredKeys,omitempty" json:"retiredKeys,omitempty"`
+}
+
+// EncryptionKey is a named, base64 encoded key.
+type EncryptionKey struct {
+	Name   string `yaml:"name" json:"name"`
+	Secret string `yaml:"secret" json:"secret"`
+}
+
+// EncryptionProvider returns the provider, applying the default.
+func (e *Encryption) EncryptionProvider() string {
+	if e.Provider == "" {
+		return EncryptionAESCBC
+	}
+	return e.Provider
+}
+
+// ValidateEncryption checks the provider and that every key has a unique name and a secret of a length the provider
+// accepts.
+func ValidateEncryption(e *Encryption) error {
+	if e == nil {
+		return nil
+	}
+	provider := e.EncryptionProvider()
+	if !slices.Contains(EncryptionProviders, provider) {
+		return fmt.Errorf("encryption: invalid provider %q, expected one of %s", e.Provider, strings.Join(EncryptionProviders, ", "))
+	}
+	if len(e.Keys) == 0 {
+		return fmt.Errorf("encryption: at least one key is required, set decrypt and then remove the section to turn encryption off")
+	}
+
+	names := make(map[string]bool)
+	for _, key := range e.Keys {
+		if key.Name == "" {
+			return fmt.Errorf("encryption: keys must have a name")
+		}
+		if names[key.Name] {
+			return fmt.Errorf("encryption: duplicate key %q", key.Name)
+		}
+		names[key.Name] = true
+
+		secret, err := base64.StdEncoding.DecodeString(key.Secret)
+		if err != nil {
+			return fmt.Errorf("encryption: key %q is not base64 encoded: %w", key.Name, err)
+		}
+		switch {
+		case provider == EncryptionSecretbox && len(secret) != 32:
+			return fmt.Errorf("encryption: key %q must be 32 bytes for secretbox, got %d", key.Name, len(secret))
+		case provider == EncryptionAESCBC && len(secret) != 16 && len(secret) != 24 && len(secret) != 32:
+			return fmt.Errorf("encryption: key %q must be 16, 24 or 32 bytes for aescbc, got %d", key.Name, len(secret))
+		}
+	}
+	for _, resource := range e.Resources {
+		if strings.TrimSpace(resource) == "" {
+			return fmt.Errorf("encryption: resources must not contain empty entries")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testKey1 = "c2VjcmV0LWtleS0xLXRoaXJ0eS10d28tYnl0ZXMhISE=" // 32 bytes
+	testKey2 = "c2VjcmV0LWtleS0yLXRoaXJ0eS10d28tYnl0ZXMhISE=" // 32 bytes
+)
+
+func Test_ValidateEncryption(t *testing.T) {
+	tests := []struct {
+		name       string
+		encryption *Encryption
+		wantErr    string
+	}{
+		{name: "No section"},
+		{name: "Rotation", encryption: &Encryption{Provider: EncryptionSecretbox, Keys: []EncryptionKey{{Name: "key2", Secret: testKey2}, {Name: "key1", Secret: testKey1}}, Resources: []string{"secrets", "configmaps"}}},
+		{name: "Unknown provider", encryption: &Encryption{Provider: "aesgcm", Keys: []EncryptionKey{{Name: "key1", Secret: testKey1}}}, wantErr: `encryption: invalid provider "aesgcm"`},
+		{name: "Decrypt", encryption: &Encryption{Keys: []EncryptionKey{{Name: "key1", Secret: testKey1}}, Decrypt: true}},
+		{name: "Only retired keys", encryption: &Encryption{RetiredKeys: []string{"key1"}}, wantErr: "encryption: at least one key is required"},
+		{name: "No keys", encryption: &Encryption{Decrypt: true}, wantErr: "encryption: at least one key is required"},
+		{name: "Unnamed key", encryption: &Encryption{Keys: []EncryptionKey{{Secret: testKey1}}}, wantErr: "encryption: keys must have a name"},
+		{name: "Duplicate key", encryption: &Encryption{Keys: []EncryptionKey{{Name: "key1", Secret: testKey1}, {Name: "key1", Secret: testKey2}}}, wantErr: `encryption: duplicate key "key1"`},
+		{name: "Not base64", encryption: &Encryption{Keys: []EncryptionKey{{Name: "key1", Secret: "not base64!"}}}, wantErr: `encryption: key "key1" is not base64 encoded`},
+		{name: "Short aescbc key", encryption: &Encryption{Keys: []EncryptionKey{{Name: "key1", Secret: "c2hvcnQ="}}}, wantErr: `encryption: key "key1" must be 16, 24 or 32 bytes for aescbc, got 5`},
+		{name: "Short secretbox key", encryption: &Encryption{Provider: EncryptionSecretbox, Keys: []EncryptionKey{{Name: "key1", Secret: "MDEyMzQ1Njc4OWFiY2RlZg=="}}}, wantErr: `encryption: key "key1" must be 32 bytes for secretbox, got 16`},
+		{name: "Empty resource", encryption: &Encryption{Keys: []EncryptionKey{{Name: "key1", Secret: testKey1}}, Resources: []string{""}}, wantErr: "encryption: resources must not contain empty entries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEncryption(tt.encryption)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateEncryption() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateEncryption() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
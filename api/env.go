@@ -0,0 +1,42 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ProxyEnvNames are the variables provider-k3s writes from the proxy settings of the cluster env. The env section
+// cannot set them.
+var ProxyEnvNames = []string{
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	"CONTAINERD_HTTP_PROXY", "CONTAINERD_HTTPS_PROXY", "CONTAINERD_NO_PROXY",
+}
+
+// ValidateEnv checks the names and values of the env section, which is written to the environment file of the k3s
+// service.
+func ValidateEnv(env map[string]string) error {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("env: invalid variable name %q", name)
+		}
+		for _, proxy := range ProxyEnvNames {
+			if strings.EqualFold(name, proxy) {
+				return fmt.Errorf("env: %s is set from the cluster env", name)
+			}
+		}
+		if strings.ContainsAny(env[name], "\n\r\x00") {
+			return fmt.Errorf("env: value of %s spans multiple lines", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Field is a config key of a k3s config struct, as named by its yaml tag.
+type Field struct {
+	Key         string
+	Type        reflect.Type
+	Description string
+}
+
+// ServerFields returns the keys supported by K3sServerConfig.
+func ServerFields() []Field {
+	return fields(reflect.TypeOf(K3sServerConfig{}))
+}
+
+// AgentFields returns the keys supported by K3sAgentConfig.
+func AgentFields() []Field {
+	return fields(reflect.TypeOf(K3sAgentConfig{}))
+}
+
+func fields(t reflect.Type) []Field {
+	var out []Field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		description, ok := Descriptions[key]
+		if !ok {
+			description = ProviderDescriptions[key]
+		}
+		out = append(out, Field{Key: key, Type: f.Type, Description: description})
+	}
+	return out
+}
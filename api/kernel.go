@@ -0,0 +1,41 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	modulePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	sysctlPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_./-]*$`)
+)
+
+// Kernel adds kernel modules and sysctls to the defaults provider-k3s derives from the k3s config.
+type Kernel struct {
+	// Modules are loaded in addition to the defaults.
+	Modules []string `yaml:"modules,omitempty" json:"modules,omitempty"`
+	// Sysctl values override the defaults of the same key.
+	Sysctl map[string]string `yaml:"sysctl,omitempty" json:"sysctl,omitempty"`
+}
+
+// ValidateKernel checks that modules and sysctls can be written to modules-load.d and sysctl.d files.
+func ValidateKernel(k *Kernel) error {
+	if k == nil {
+		return nil
+	}
+	for _, m := range k.Modules {
+		if !modulePattern.MatchString(m) {
+			return fmt.Errorf("kernel: invalid module name %q", m)
+		}
+	}
+	for key, value := range k.Sysctl {
+		if !sysctlPattern.MatchString(key) {
+			return fmt.Errorf("kernel: invalid sysctl %q", key)
+		}
+		if strings.ContainsAny(value, "\n\r\x00") {
+			return fmt.Errorf("kernel: value of sysctl %s spans multiple lines", key)
+		}
+	}
+	return nil
+}
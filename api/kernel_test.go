@@ -0,0 +1,34 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ValidateKernel(t *testing.T) {
+	tests := []struct {
+		name    string
+		kernel  *Kernel
+		wantErr string
+	}{
+		{name: "No section"},
+		{name: "Valid", kernel: &Kernel{Modules: []string{"ip_vs", "nf_conntrack"}, Sysctl: map[string]string{"fs.inotify.max_user_instances": "8192"}}},
+		{name: "Invalid module", kernel: &Kernel{Modules: []string{"ip_vs; reboot"}}, wantErr: `kernel: invalid module name "ip_vs; reboot"`},
+		{name: "Invalid sysctl", kernel: &Kernel{Sysctl: map[string]string{"net.ipv4.ip_forward = 1\nkernel.panic": "1"}}, wantErr: "kernel: invalid sysctl"},
+		{name: "Multi-line", kernel: &Kernel{Sysctl: map[string]string{"kernel.panic": "1\n2"}}, wantErr: "kernel: value of sysctl kernel.panic spans multiple lines"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateKernel(tt.kernel)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateKernel() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateKernel() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// KubeletEvictionSignals are the signals eviction thresholds may be set for.
+	KubeletEvictionSignals = []string{
+		"memory.available",
+		"nodefs.available",
+		"nodefs.inodesFree",
+		"imagefs.available",
+		"imagefs.inodesFree",
+		"containerfs.available",
+		"containerfs.inodesFree",
+		"pid.available",
+	}
+	// KubeletReservedResources are the resources kubeReserved and systemReserved may hold.
+	KubeletReservedResources = []string{"cpu", "memory", "ephemeral-storage", "pid"}
+)
+
+var quantityPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(m|k|M|G|T|P|E|Ki|Mi|Gi|Ti|Pi|Ei)?$`)
+
+// Kubelet holds KubeletConfiguration fields, see
+// https://kubernetes.io/docs/reference/config-api/kubelet-config.v1beta1/. The field names match the file.
+type Kubelet struct {
+	MaxPods *int `yaml:"maxPods,omitempty" json:"maxPods,omitempty"`
+
+	// Eviction thresholds map a signal to a quantity or a percentage.
+	EvictionHard              map[string]string `yaml:"evictionHard,omitempty" json:"evictionHard,omitempty"`
+	EvictionSoft              map[string]string `yaml:"evictionSoft,omitempty" json:"evictionSoft,omitempty"`
+	EvictionSoftGracePeriod   map[string]string `yaml:"evictionSoftGracePeriod,omitempty" json:"evictionSoftGracePeriod,omitempty"`
+	EvictionMaxPodGracePeriod *int              `yaml:"evictionMaxPodGracePeriod,omitempty" json:"evictionMaxPodGracePeriod,omitempty"`
+
+	KubeReserved   map[string]string `yaml:"kubeReserved,omitempty" json:"kubeReserved,omitempty"`
+	SystemReserved map[string]string `yaml:"systemReserved,omitempty" json:"systemReserved,omitempty"`
+
+	ImageGCHighThresholdPercent *int   `yaml:"imageGCHighThresholdPercent,omitempty" json:"imageGCHighThresholdPercent,omitempty"`
+	ImageGCLowThresholdPercent  *int   `yaml:"imageGCLowThresholdPercent,omitempty" json:"imageGCLowThresholdPercent,omitempty"`
+	ImageMinimumGCAge           string `yaml:"imageMinimumGCAge,omitempty" json:"imageMinimumGCAge,omitempty"`
+
+	ShutdownGracePeriod             string `yaml:"shutdownGracePeriod,omitempty" json:"shutdownGracePeriod,omitempty"`
+	ShutdownGracePeriodCriticalPods string `yaml:"shutdownGracePeriodCriticalPods,omitempty" json:"shutdownGracePeriodCriticalPods,omitempty"`
+}
+
+// ValidateKubelet checks the values the kubelet would otherwise refuse at startup.
+func ValidateKubelet(k *Kubelet) error {
+	if k == nil {
+		return nil
+	}
+	if k.MaxPods != nil && *k.MaxPods <= 0 {
+		return fmt.Errorf("kubelet: maxPods must be positive")
+	}
+	if k.EvictionMaxPodGracePeriod != nil && *k.EvictionMaxPodGracePeriod < 0 {
+		return fmt.Errorf("kubelet: evictionMaxPodGracePeriod must not be negative")
+	}
+
+	for name, thresholds := range map[string]map[string]string{"evictionHard": k.EvictionHard, "evictionSoft": k.EvictionSoft} {
+		for signal, value := range thresholds {
+			if !slices.Contains(KubeletEvictionSignals, signal) {
+				return fmt.Errorf("kubelet: %s has unknown signal %q, expected one of %s", name, signal, strings.Join(KubeletEvictionSignals, ", "))
+			}
+			if !validThreshold(value) {
+				return fmt.Errorf("kubelet: %s %s must be a quantity or a percentage, got %q", name, signal, value)
+			}
+		}
+	}
+	for signal, period := range k.EvictionSoftGracePeriod {
+		if _, ok := k.EvictionSoft[signal]; !ok {
+			return fmt.Errorf("kubelet: evictionSoftGracePeriod %s has no evictionSoft threshold", signal)
+		}
+		if err := validDuration(period); err != nil {
+			return fmt.Errorf("kubelet: evictionSoftGracePeriod %s: %w", signal, err)
+		}
+	}
+	for signal := range k.EvictionSoft {
+		if _, ok := k.EvictionSoftGracePeriod[signal]; !ok {
+			return fmt.Errorf("kubelet: evictionSoft %s needs an evictionSoftGracePeriod", signal)
+		}
+	}
+
+	for name, reserved := range map[string]map[string]string{"kubeReserved": k.KubeReserved, "systemReserved": k.SystemReserved} {
+		for resource, value := range reserved {
+			if !slices.Contains(KubeletReservedResources, resource) {
+				return fmt.Errorf("kubelet: %s has unknown resource %q, expected one of %s", name, resource, strings.Join(KubeletReservedResources, ", "))
+			}
+			if !quantityPattern.MatchString(value) {
+				return fmt.Errorf("kubelet: %s %s must be a quantity, got %q", name, resource, value)
+			}
+		}
+	}
+
+	for name, v := range map[string]*int{"imageGCHighThresholdPercent": k.ImageGCHighThresholdPercent, "imageGCLowThresholdPercent": k.ImageGCLowThresholdPercent} {
+		if v != nil && (*v < 0 || *v > 100) {
+			return fmt.Errorf("kubelet: %s must be between 0 and 100", name)
+		}
+	}
+	if k.ImageGCHighThresholdPercent != nil && k.ImageGCLowThresholdPercent != nil && *k.ImageGCLowThresholdPercent >= *k.ImageGCHighThresholdPercent {
+		return fmt.Errorf("kubelet: imageGCLowThresholdPercent must be lower than imageGCHighThresholdPercent")
+	}
+
+	durations := make(map[string]time.Duration)
+	for name, v := range map[string]string{
+		"imageMinimumGCAge":               k.ImageMinimumGCAge,
+		"shutdownGracePeriod":             k.ShutdownGracePeriod,
+		"shutdownGracePeriodCriticalPods": k.ShutdownGracePeriodCriticalPods,
+	} {
+		if v == "" {
+			continue
+		}
+		if err := validDuration(v); err != nil {
+			return fmt.Errorf("kubelet: %s: %w", name, err)
+		}
+		durations[name], _ = time.ParseDuration(v)
+	}
+	if durations["shutdownGracePeriodCriticalPods"] > durations["shutdownGracePeriod"] {
+		return fmt.Errorf("kubelet: shutdownGracePeriodCriticalPods must not exceed shutdownGracePeriod")
+	}
+	return nil
+}
+
+// validThreshold accepts a quantity or a percentage up to 100%.
+func validThreshold(value string) bool {
+	if percent, ok := strings.CutSuffix(value, "%"); ok {
+		v, err := strconv.ParseFloat(percent, 64)
+		return err == nil && v >= 0 && v <= 100
+	}
+	return quantityPattern.MatchString(value)
+}
+
+func validDuration(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", value)
+	}
+	if d < 0 {
+		return fmt.Errorf("duration %q must not be negative", value)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ValidateKubelet(t *testing.T) {
+	tests := []struct {
+		name    string
+		kubelet *Kubelet
+		wantErr string
+	}{
+		{name: "No section"},
+		{name: "Valid", kubelet: &Kubelet{
+			MaxPods:                     intPtr(200),
+			EvictionHard:                map[string]string{"memory.available": "500Mi", "nodefs.available": "10%"},
+			EvictionSoft:                map[string]string{"memory.available": "1Gi"},
+			EvictionSoftGracePeriod:     map[string]string{"memory.available": "1m30s"},
+			KubeReserved:                map[string]string{"cpu": "500m", "memory": "1Gi"},
+			ImageGCHighThresholdPercent: intPtr(85),
+			ImageGCLowThresholdPercent:  intPtr(80),
+			ShutdownGracePeriod:         "30s",
+		}},
+		{name: "Zero max pods", kubelet: &Kubelet{MaxPods: intPtr(0)}, wantErr: "kubelet: maxPods must be positive"},
+		{name: "Unknown signal", kubelet: &Kubelet{EvictionHard: map[string]string{"disk.available": "10%"}}, wantErr: `kubelet: evictionHard has unknown signal "disk.available"`},
+		{name: "Invalid threshold", kubelet: &Kubelet{EvictionHard: map[string]string{"memory.available": "lots"}}, wantErr: `kubelet: evictionHard memory.available must be a quantity or a percentage, got "lots"`},
+		{name: "Percentage above 100", kubelet: &Kubelet{EvictionHard: map[string]string{"nodefs.available": "150%"}}, wantErr: "kubelet: evictionHard nodefs.available must be a quantity or a percentage"},
+		{name: "Soft without grace", kubelet: &Kubelet{EvictionSoft: map[string]string{"memory.available": "1Gi"}}, wantErr: "kubelet: evictionSoft memory.available needs an evictionSoftGracePeriod"},
+		{name: "Grace without soft", kubelet: &Kubelet{EvictionSoftGracePeriod: map[string]string{"memory.available": "1m"}}, wantErr: "kubelet: evictionSoftGracePeriod memory.available has no evictionSoft threshold"},
+		{name: "Unknown reserved", kubelet: &Kubelet{KubeReserved: map[string]string{"gpu": "1"}}, wantErr: `kubelet: kubeReserved has unknown resource "gpu"`},
+		{name: "Invalid reserved", kubelet: &Kubelet{SystemReserved: map[string]string{"memory": "1 GB"}}, wantErr: `kubelet: systemReserved memory must be a quantity, got "1 GB"`},
+		{name: "GC thresholds reversed", kubelet: &Kubelet{ImageGCHighThresholdPercent: intPtr(60), ImageGCLowThresholdPercent: intPtr(80)}, wantErr: "kubelet: imageGCLowThresholdPercent must be lower than imageGCHighThresholdPercent"},
+		{name: "GC threshold above 100", kubelet: &Kubelet{ImageGCHighThresholdPercent: intPtr(101)}, wantErr: "kubelet: imageGCHighThresholdPercent must be between 0 and 100"},
+		{name: "Invalid duration", kubelet: &Kubelet{ImageMinimumGCAge: "2 minutes"}, wantErr: `kubelet: imageMinimumGCAge: invalid duration "2 minutes"`},
+		{name: "Critical exceeds period", kubelet: &Kubelet{ShutdownGracePeriod: "10s", ShutdownGracePeriodCriticalPods: "30s"}, wantErr: "kubelet: shutdownGracePeriodCriticalPods must not exceed shutdownGracePeriod"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateKubelet(tt.kubelet)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateKubelet() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateKubelet() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
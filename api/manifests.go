@@ -0,0 +1,71 @@
+package api
+
+import "fmt"
+
+// MaxManifestOrder is the highest order, so the two digit prefixes sort in the same order as the numbers.
+const MaxManifestOrder = 99
+
+// Manifest is a raw Kubernetes manifest that k3s applies from its auto-deploy directory.
+type Manifest struct {
+	// Name is the file name without extension.
+	Name string `yaml:"name" json:"name"`
+	// Order prefixes the file name, so manifests are applied in ascending order. It ranges from 0 to
+	// MaxManifestOrder.
+	Order   *int   `yaml:"order,omitempty" json:"order,omitempty"`
+	Content string `yaml:"content" json:"content"`
+}
+
+const (
+	HelmChartKind       = "HelmChart"
+	HelmChartConfigKind = "HelmChartConfig"
+)
+
+// Chart is rendered into a HelmChart, or a HelmChartConfig when Kind says so, see
+// https://docs.k3s.io/helm#using-the-helm-controller.
+type Chart struct {
+	Name      string `yaml:"name" json:"name"`
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	Kind      string `yaml:"kind,omitempty" json:"kind,omitempty"`
+	Order     *int   `yaml:"order,omitempty" json:"order,omitempty"`
+
+	Chart           string            `yaml:"chart,omitempty" json:"chart,omitempty"`
+	Repo            string            `yaml:"repo,omitempty" json:"repo,omitempty"`
+	RepoCA          string            `yaml:"repoCA,omitempty" json:"repoCA,omitempty"`
+	Version         string            `yaml:"version,omitempty" json:"version,omitempty"`
+	ChartContent    string            `yaml:"chartContent,omitempty" json:"chartContent,omitempty"`
+	TargetNamespace string            `yaml:"targetNamespace,omitempty" json:"targetNamespace,omitempty"`
+	CreateNamespace bool              `yaml:"createNamespace,omitempty" json:"createNamespace,omitempty"`
+	Bootstrap       bool              `yaml:"bootstrap,omitempty" json:"bootstrap,omitempty"`
+	HelmVersion     string            `yaml:"helmVersion,omitempty" json:"helmVersion,omitempty"`
+	JobImage        string            `yaml:"jobImage,omitempty" json:"jobImage,omitempty"`
+	Timeout         string            `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	FailurePolicy   string            `yaml:"failurePolicy,omitempty" json:"failurePolicy,omitempty"`
+	Set             map[string]string `yaml:"set,omitempty" json:"set,omitempty"`
+
+	// Values is rendered into valuesContent, after any ValuesContent given verbatim.
+	Values        map[string]interface{} `yaml:"values,omitempty" json:"values,omitempty"`
+	ValuesContent string                 `yaml:"valuesContent,omitempty" json:"valuesContent,omitempty"`
+}
+
+// ValidateManifests checks the order of the manifests and charts sections. The other rules are checked when the
+// files are rendered.
+func ValidateManifests(manifests []Manifest, charts []Chart) error {
+	for _, m := range manifests {
+		if err := validateManifestOrder("manifests", m.Name, m.Order); err != nil {
+			return err
+		}
+	}
+	for _, c := range charts {
+		if err := validateManifestOrder("charts", c.Name, c.Order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateManifestOrder(section, name string, order *int) error {
+	if order != nil && (*order < 0 || *order > MaxManifestOrder) {
+		return fmt.Errorf("%s: %s: order %d must be between 0 and %d", section, name, *order, MaxManifestOrder)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ValidateManifests(t *testing.T) {
+	tests := []struct {
+		name      string
+		manifests []Manifest
+		charts    []Chart
+		wantErr   string
+	}{
+		{name: "No sections"},
+		{name: "Orders in range", manifests: []Manifest{{Name: "first", Order: intPtr(0)}, {Name: "unordered"}}, charts: []Chart{{Name: "last", Order: intPtr(99)}}},
+		{name: "Manifest order above 99", manifests: []Manifest{{Name: "late", Order: intPtr(100)}}, wantErr: "manifests: late: order 100 must be between 0 and 99"},
+		{name: "Negative chart order", charts: []Chart{{Name: "early", Order: intPtr(-1)}}, wantErr: "charts: early: order -1 must be between 0 and 99"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateManifests(tt.manifests, tt.charts)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateManifests() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateManifests() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// OIDCSigningAlgs are the JWT signing algorithms the API server accepts.
+var OIDCSigningAlgs = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256", "PS384", "PS512"}
+
+// OIDC authenticates API server users with tokens from an OpenID Connect issuer.
+type OIDC struct {
+	IssuerURL string `yaml:"issuerURL" json:"issuerURL"`
+	ClientID  string `yaml:"clientID" json:"clientID"`
+
+	// UsernameClaim defaults to sub. Unless it is email, usernames are prefixed with the issuer URL and # by default;
+	// a UsernamePrefix of - turns the prefix off.
+	UsernameClaim  string `yaml:"usernameClaim,omitempty" json:"usernameClaim,omitempty"`
+	UsernamePrefix string `yaml:"usernamePrefix,omitempty" json:"usernamePrefix,omitempty"`
+	GroupsClaim    string `yaml:"groupsClaim,omitempty" json:"groupsClaim,omitempty"`
+	GroupsPrefix   string `yaml:"groupsPrefix,omitempty" json:"groupsPrefix,omitempty"`
+
+	// RequiredClaims must be present in the token with the given values.
+	RequiredClaims map[string]string `yaml:"requiredClaims,omitempty" json:"requiredClaims,omitempty"`
+	SigningAlgs    []string          `yaml:"signingAlgs,omitempty" json:"signingAlgs,omitempty"`
+
+	// CA is a PEM bundle that verifies the issuer, instead of the host trust store.
+	CA string `yaml:"ca,omitempty" json:"ca,omitempty"`
+
+	// AuthenticationConfig renders a structured AuthenticationConfiguration instead of the oidc flags. It needs
+	// Kubernetes 1.30 or newer.
+	AuthenticationConfig bool `yaml:"authenticationConfig,omitempty" json:"authenticationConfig,omitempty"`
+}
+
+// ValidateOIDC checks the issuer URL, claims, signing algorithms and CA bundle.
+func ValidateOIDC(o *OIDC) error {
+	if o == nil {
+		return nil
+	}
+
+	issuer, err := url.Parse(o.IssuerURL)
+	if err != nil || issuer.Scheme != "https" || issuer.Host == "" || issuer.RawQuery != "" || issuer.Fragment != "" || issuer.User != nil {
+		return fmt.Errorf("oidc: issuerURL %q must be an https URL without query, fragment or credentials", o.IssuerURL)
+	}
+	if o.ClientID == "" {
+		return fmt.Errorf("oidc: clientID is required")
+	}
+
+	claims := map[string]string{"usernameClaim": o.UsernameClaim, "groupsClaim": o.GroupsClaim}
+	for claim := range o.RequiredClaims {
+		if !validClaim(claim) {
+			return fmt.Errorf("oidc: invalid required claim %q", claim)
+		}
+	}
+	for name, claim := range claims {
+		if claim != "" && !validClaim(claim) {
+			return fmt.Errorf("oidc: invalid %s %q", name, claim)
+		}
+	}
+	for _, v := range []string{o.UsernamePrefix, o.GroupsPrefix, o.ClientID} {
+		if strings.ContainsAny(v, ",\n\r") {
+			return fmt.Errorf("oidc: clientID and prefixes must not contain commas or newlines")
+		}
+	}
+
+	for _, alg := range o.SigningAlgs {
+		if !slices.Contains(OIDCSigningAlgs, alg) {
+			return fmt.Errorf("oidc: invalid signing algorithm %q, expected one of %s", alg, strings.Join(OIDCSigningAlgs, ", "))
+		}
+	}
+	if o.AuthenticationConfig && len(o.SigningAlgs) > 0 {
+		return fmt.Errorf("oidc: signingAlgs is not supported with authenticationConfig")
+	}
+
+	if o.CA != "" {
+		if err := validatePEMCertificates(o.CA); err != nil {
+			return fmt.Errorf("oidc: invalid ca: %w", err)
+		}
+	}
+	return nil
+}
+
+func validClaim(claim string) bool {
+	return claim != "" && !strings.ContainsAny(claim, " \t\n\r,=")
+}
+
+// validatePEMCertificates checks that bundle holds at least one certificate and nothing else.
+func validatePEMCertificates(bundle string) error {
+	rest := []byte(bundle)
+	count := 0
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("unexpected %s block", block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return err
+		}
+		count++
+	}
+	if count == 0 || strings.TrimSpace(string(rest)) != "" {
+		return fmt.Errorf("expected PEM encoded certificates")
+	}
+	return nil
+}
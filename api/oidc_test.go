@@ -0,0 +1,40 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ValidateOIDC(t *testing.T) {
+	tests := []struct {
+		name    string
+		oidc    *OIDC
+		wantErr string
+	}{
+		{name: "No section"},
+		{name: "Valid", oidc: &OIDC{IssuerURL: "https://dex.example.com/dex", ClientID: "kubernetes", GroupsClaim: "groups", RequiredClaims: map[string]string{"hd": "example.com"}, SigningAlgs: []string{"RS256", "ES256"}}},
+		{name: "Plain http issuer", oidc: &OIDC{IssuerURL: "http://dex.example.com", ClientID: "kubernetes"}, wantErr: `oidc: issuerURL "http://dex.example.com" must be an https URL`},
+		{name: "Issuer with query", oidc: &OIDC{IssuerURL: "https://dex.example.com?tenant=a", ClientID: "kubernetes"}, wantErr: "must be an https URL without query"},
+		{name: "Missing client", oidc: &OIDC{IssuerURL: "https://dex.example.com"}, wantErr: "oidc: clientID is required"},
+		{name: "Invalid claim", oidc: &OIDC{IssuerURL: "https://dex.example.com", ClientID: "kubernetes", GroupsClaim: "my groups"}, wantErr: `oidc: invalid groupsClaim "my groups"`},
+		{name: "Invalid required", oidc: &OIDC{IssuerURL: "https://dex.example.com", ClientID: "kubernetes", RequiredClaims: map[string]string{"a=b": "c"}}, wantErr: `oidc: invalid required claim "a=b"`},
+		{name: "Comma in client", oidc: &OIDC{IssuerURL: "https://dex.example.com", ClientID: "a,b"}, wantErr: "oidc: clientID and prefixes must not contain commas or newlines"},
+		{name: "Unknown algorithm", oidc: &OIDC{IssuerURL: "https://dex.example.com", ClientID: "kubernetes", SigningAlgs: []string{"HS256"}}, wantErr: `oidc: invalid signing algorithm "HS256"`},
+		{name: "Algorithms structured", oidc: &OIDC{IssuerURL: "https://dex.example.com", ClientID: "kubernetes", SigningAlgs: []string{"RS256"}, AuthenticationConfig: true}, wantErr: "oidc: signingAlgs is not supported with authenticationConfig"},
+		{name: "Invalid CA", oidc: &OIDC{IssuerURL: "https://dex.example.com", ClientID: "kubernetes", CA: "-----BEGIN CERTIFICATE-----\nbm90IGEgY2VydA==\n-----END CERTIFICATE-----\n"}, wantErr: "oidc: invalid ca"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOIDC(tt.oidc)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateOIDC() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateOIDC() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Pod Security Standards levels, see https://kubernetes.io/docs/concepts/security/pod-security-standards/.
+const (
+	PodSecurityPrivileged = "privileged"
+	PodSecurityBaseline   = "baseline"
+	PodSecurityRestricted = "restricted"
+)
+
+var PodSecurityLevels = []string{PodSecurityPrivileged, PodSecurityBaseline, PodSecurityRestricted}
+
+var podSecurityVersionPattern = regexp.MustCompile(`^(latest|v1\.[0-9]+)$`)
+
+// PodSecurity sets the cluster-wide defaults of the PodSecurity admission plugin. Unset levels default to privileged
+// and unset versions to latest, as in Kubernetes.
+type PodSecurity struct {
+	Enforce        string `yaml:"enforce,omitempty" json:"enforce,omitempty"`
+	EnforceVersion string `yaml:"enforceVersion,omitempty" json:"enforceVersion,omitempty"`
+	Audit          string `yaml:"audit,omitempty" json:"audit,omitempty"`
+	AuditVersion   string `yaml:"auditVersion,omitempty" json:"auditVersion,omitempty"`
+	Warn           string `yaml:"warn,omitempty" json:"warn,omitempty"`
+	WarnVersion    string `yaml:"warnVersion,omitempty" json:"warnVersion,omitempty"`
+
+	Exemptions PodSecurityExemptions `yaml:"exemptions,omitempty" json:"exemptions,omitempty"`
+}
+
+// PodSecurityExemptions are never checked by the PodSecurity admission plugin.
+type PodSecurityExemptions struct {
+	Namespaces     []string `yaml:"namespaces,omitempty" json:"namespaces,omitempty"`
+	Usernames      []string `yaml:"usernames,omitempty" json:"usernames,omitempty"`
+	RuntimeClasses []string `yaml:"runtimeClasses,omitempty" json:"runtimeClasses,omitempty"`
+}
+
+// ValidatePodSecurity checks the levels and versions.
+func ValidatePodSecurity(p *PodSecurity) error {
+	if p == nil {
+		return nil
+	}
+	for _, mode := range []struct{ name, level, version string }{
+		{"enforce", p.Enforce, p.EnforceVersion},
+		{"audit", p.Audit, p.AuditVersion},
+		{"warn", p.Warn, p.WarnVersion},
+	} {
+		if mode.level != "" && !slices.Contains(PodSecurityLevels, mode.level) {
+			return fmt.Errorf("podSecurity: invalid %s level %q, expected one of %s", mode.name, mode.level, strings.Join(PodSecurityLevels, ", "))
+		}
+		if mode.version != "" && !podSecurityVersionPattern.MatchString(mode.version) {
+			return fmt.Errorf("podSecurity: invalid %sVersion %q, expected latest or v1.<minor>", mode.name, mode.version)
+		}
+	}
+	for name, values := range map[string][]string{
+		"namespaces":     p.Exemptions.Namespaces,
+		"usernames":      p.Exemptions.Usernames,
+		"runtimeClasses": p.Exemptions.RuntimeClasses,
+	} {
+		for _, v := range values {
+			if strings.TrimSpace(v) == "" {
+				return fmt.Errorf("podSecurity: exemptions.%s must not contain empty entries", name)
+			}
+		}
+	}
+	return nil
+}
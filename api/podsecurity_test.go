@@ -0,0 +1,34 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ValidatePodSecurity(t *testing.T) {
+	tests := []struct {
+		name        string
+		podSecurity *PodSecurity
+		wantErr     string
+	}{
+		{name: "No section"},
+		{name: "Levels and exemptions", podSecurity: &PodSecurity{Enforce: PodSecurityBaseline, EnforceVersion: "v1.29", Exemptions: PodSecurityExemptions{Namespaces: []string{"kube-system"}}}},
+		{name: "Unknown level", podSecurity: &PodSecurity{Enforce: "strict"}, wantErr: `podSecurity: invalid enforce level "strict"`},
+		{name: "Invalid version", podSecurity: &PodSecurity{Warn: PodSecurityBaseline, WarnVersion: "1.29"}, wantErr: `podSecurity: invalid warnVersion "1.29"`},
+		{name: "Empty exemption", podSecurity: &PodSecurity{Exemptions: PodSecurityExemptions{Namespaces: []string{" "}}}, wantErr: "podSecurity: exemptions.namespaces must not contain empty entries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePodSecurity(tt.podSecurity)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidatePodSecurity() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidatePodSecurity() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
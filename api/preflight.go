@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Names of the pre-flight checks.
+const (
+	PreflightCgroups = "cgroups"
+	PreflightModules = "modules"
+	PreflightPorts   = "ports"
+	PreflightDisk    = "disk"
+	PreflightClock   = "clock"
+)
+
+// PreflightChecks lists every pre-flight check in the order they run.
+var PreflightChecks = []string{PreflightCgroups, PreflightModules, PreflightPorts, PreflightDisk, PreflightClock}
+
+// Preflight configures the host checks that run before k3s is started.
+type Preflight struct {
+	// Block keeps the k3s service from being started when a check fails. Otherwise failures are only reported.
+	Block bool `yaml:"block,omitempty" json:"block,omitempty"`
+	// Skip names checks that are not run.
+	Skip []string `yaml:"skip,omitempty" json:"skip,omitempty"`
+}
+
+// ValidatePreflight checks that the skipped checks exist.
+func ValidatePreflight(p *Preflight) error {
+	if p == nil {
+		return nil
+	}
+	for _, name := range p.Skip {
+		if !slices.Contains(PreflightChecks, name) {
+			return fmt.Errorf("preflight: unknown check %q, expected one of %s", name, strings.Join(PreflightChecks, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ProfileCIS hardens server nodes following the k3s CIS hardening guide, see https://docs.k3s.io/security/hardening-guide.
+const ProfileCIS = "cis"
+
+// Profiles lists the supported profiles.
+var Profiles = []string{ProfileCIS}
+
+// ValidateProfile checks that the profile exists.
+func ValidateProfile(profile string) error {
+	if profile != "" && !slices.Contains(Profiles, profile) {
+		return fmt.Errorf("profile: unknown profile %q, expected one of %s", profile, strings.Join(Profiles, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProviderConfig holds the sections of cluster.config that configure provider-k3s itself rather than k3s. They are
+// removed from the section before the rest is written as k3s config.
+type ProviderConfig struct {
+	Registries *Registries `yaml:"registries,omitempty" json:"registries,omitempty"`
+	Manifests  []Manifest  `yaml:"manifests,omitempty" json:"manifests,omitempty"`
+	Charts     []Chart     `yaml:"charts,omitempty" json:"charts,omitempty"`
+
+	// Env holds variables for the environment file of the k3s or k3s-agent service.
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+
+	Service *Service `yaml:"service,omitempty" json:"service,omitempty"`
+
+	Preflight *Preflight `yaml:"preflight,omitempty" json:"preflight,omitempty"`
+
+	Kernel *Kernel `yaml:"kernel,omitempty" json:"kernel,omitempty"`
+
+	Swap *Swap `yaml:"swap,omitempty" json:"swap,omitempty"`
+
+	Audit *Audit `yaml:"audit,omitempty" json:"audit,omitempty"`
+
+	PodSecurity *PodSecurity `yaml:"podSecurity,omitempty" json:"podSecurity,omitempty"`
+
+	Encryption *Encryption `yaml:"encryption,omitempty" json:"encryption,omitempty"`
+
+	OIDC *OIDC `yaml:"oidc,omitempty" json:"oidc,omitempty"`
+
+	// Kubelet is rendered into a KubeletConfiguration file on every node.
+	Kubelet *Kubelet `yaml:"kubelet,omitempty" json:"kubelet,omitempty"`
+
+	// Profile layers a hardened k3s config under the cluster config on server nodes.
+	Profile string `yaml:"profile,omitempty" json:"profile,omitempty"`
+}
+
+// ServerOnlyProviderConfigKeys are the ProviderConfig sections that only apply to init and controlplane nodes.
+var ServerOnlyProviderConfigKeys = []string{
+	"manifests",
+	"charts",
+	"profile",
+	"audit",
+	"podSecurity",
+	"encryption",
+	"oidc",
+}
+
+// ProviderConfigFields returns the top-level sections of ProviderConfig.
+func ProviderConfigFields() []Field {
+	return fields(reflect.TypeOf(ProviderConfig{}))
+}
+
+// IsProviderConfigKey reports whether key is a ProviderConfig section rather than a k3s flag.
+func IsProviderConfigKey(key string) bool {
+	for _, f := range ProviderConfigFields() {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the sections whose values must follow rules beyond their type.
+func (c ProviderConfig) Validate() error {
+	if err := ValidateManifests(c.Manifests, c.Charts); err != nil {
+		return err
+	}
+	if err := ValidateEnv(c.Env); err != nil {
+		return err
+	}
+	if err := ValidateService(c.Service); err != nil {
+		return err
+	}
+	if err := ValidatePreflight(c.Preflight); err != nil {
+		return err
+	}
+	if err := ValidateKernel(c.Kernel); err != nil {
+		return err
+	}
+	if err := ValidateSwap(c.Swap); err != nil {
+		return err
+	}
+	if err := ValidateProfile(c.Profile); err != nil {
+		return err
+	}
+	if err := ValidateAudit(c.Audit); err != nil {
+		return err
+	}
+	if err := ValidatePodSecurity(c.PodSecurity); err != nil {
+		return err
+	}
+	if err := ValidateEncryption(c.Encryption); err != nil {
+		return err
+	}
+	if err := ValidateOIDC(c.OIDC); err != nil {
+		return err
+	}
+	return ValidateKubelet(c.Kubelet)
+}
+
+// ParseProviderConfig decodes the provider sections of the cluster options. Unlike k3s flags, unknown keys inside a
+// section are an error.
+func ParseProviderConfig(options map[string]interface{}) (ProviderConfig, error) {
+	var cfg ProviderConfig
+
+	sections := make(map[string]interface{})
+	for k, v := range options {
+		if IsProviderConfigKey(k) {
+			sections[k] = v
+		}
+	}
+	if len(sections) == 0 {
+		return cfg, nil
+	}
+
+	data, err := yaml.Marshal(sections)
+	if err != nil {
+		return cfg, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("failed to un-marshal provider sections of cluster options: %w", err)
+	}
+	return cfg, nil
+}
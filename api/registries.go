@@ -0,0 +1,36 @@
+package api
+
+// Registries is rendered into the k3s registries.yaml, see https://docs.k3s.io/installation/private-registry.
+type Registries struct {
+	Mirrors map[string]RegistryMirror `yaml:"mirrors,omitempty" json:"mirrors,omitempty"`
+	Configs map[string]RegistryConfig `yaml:"configs,omitempty" json:"configs,omitempty"`
+}
+
+type RegistryMirror struct {
+	Endpoints []string          `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	Rewrites  map[string]string `yaml:"rewrite,omitempty" json:"rewrite,omitempty"`
+}
+
+type RegistryConfig struct {
+	Auth *RegistryAuth `yaml:"auth,omitempty" json:"auth,omitempty"`
+	TLS  *RegistryTLS  `yaml:"tls,omitempty" json:"tls,omitempty"`
+}
+
+type RegistryAuth struct {
+	Username      string `yaml:"username,omitempty" json:"username,omitempty"`
+	Password      string `yaml:"password,omitempty" json:"password,omitempty"`
+	Auth          string `yaml:"auth,omitempty" json:"auth,omitempty"`
+	IdentityToken string `yaml:"identitytoken,omitempty" json:"identitytoken,omitempty"`
+}
+
+// RegistryTLS takes certificates either as paths on the node or inline as PEM. Inline certificates are written to
+// files by the provider and replaced by their paths in registries.yaml.
+type RegistryTLS struct {
+	CAFile             string `yaml:"ca_file,omitempty" json:"ca_file,omitempty"`
+	CertFile           string `yaml:"cert_file,omitempty" json:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty" json:"key_file,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty"`
+	CA                 string `yaml:"ca,omitempty" json:"ca,omitempty"`
+	Cert               string `yaml:"cert,omitempty" json:"cert,omitempty"`
+	Key                string `yaml:"key,omitempty" json:"key,omitempty"`
+}
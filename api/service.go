@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Service tunes the k3s or k3s-agent service. It is rendered into a systemd drop-in, and into an OpenRC settings file
+// sourced by the conf.d file of the service for the settings OpenRC has an equivalent for.
+type Service struct {
+	// LimitNOFILE and LimitNPROC take a number or infinity.
+	LimitNOFILE string `yaml:"limitNOFILE,omitempty" json:"limitNOFILE,omitempty"`
+	LimitNPROC  string `yaml:"limitNPROC,omitempty" json:"limitNPROC,omitempty"`
+
+	TimeoutStartSec string `yaml:"timeoutStartSec,omitempty" json:"timeoutStartSec,omitempty"`
+	Restart         string `yaml:"restart,omitempty" json:"restart,omitempty"`
+	RestartSec      string `yaml:"restartSec,omitempty" json:"restartSec,omitempty"`
+	Delegate        string `yaml:"delegate,omitempty" json:"delegate,omitempty"`
+
+	// After, Wants and Requires name units the service is ordered after or depends on. On OpenRC, .service units are
+	// used as service names and other unit types are skipped.
+	After    []string `yaml:"after,omitempty" json:"after,omitempty"`
+	Wants    []string `yaml:"wants,omitempty" json:"wants,omitempty"`
+	Requires []string `yaml:"requires,omitempty" json:"requires,omitempty"`
+}
+
+// RestartValues are the values systemd accepts for Restart.
+var RestartValues = []string{"no", "always", "on-success", "on-failure", "on-abnormal", "on-abort", "on-watchdog"}
+
+// ValidateService checks the service section for values that cannot be rendered into a unit file.
+func ValidateService(s *Service) error {
+	if s == nil {
+		return nil
+	}
+
+	values := map[string]string{
+		"limitNOFILE":     s.LimitNOFILE,
+		"limitNPROC":      s.LimitNPROC,
+		"timeoutStartSec": s.TimeoutStartSec,
+		"restart":         s.Restart,
+		"restartSec":      s.RestartSec,
+		"delegate":        s.Delegate,
+	}
+	for _, key := range []string{"limitNOFILE", "limitNPROC", "timeoutStartSec", "restart", "restartSec", "delegate"} {
+		if strings.ContainsAny(values[key], "\n\r\x00") {
+			return fmt.Errorf("service: %s spans multiple lines", key)
+		}
+	}
+	limits := map[string]string{"limitNOFILE": s.LimitNOFILE, "limitNPROC": s.LimitNPROC}
+	for _, key := range []string{"limitNOFILE", "limitNPROC"} {
+		if v := limits[key]; v != "" && v != "infinity" {
+			if _, err := strconv.ParseUint(v, 10, 64); err != nil {
+				return fmt.Errorf("service: %s must be a number or infinity, got %q", key, v)
+			}
+		}
+	}
+	if s.Restart != "" && !slices.Contains(RestartValues, s.Restart) {
+		return fmt.Errorf("service: invalid restart %q, expected one of %s", s.Restart, strings.Join(RestartValues, ", "))
+	}
+
+	units := map[string][]string{"after": s.After, "wants": s.Wants, "requires": s.Requires}
+	for _, key := range []string{"after", "wants", "requires"} {
+		for _, unit := range units[key] {
+			if unit == "" || strings.ContainsAny(unit, " \t\n\r\x00") {
+				return fmt.Errorf("service: invalid unit %q in %s", unit, key)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ValidateService(t *testing.T) {
+	tests := []struct {
+		name    string
+		service *Service
+		wantErr string
+	}{
+		{name: "No section"},
+		{name: "Valid", service: &Service{
+			LimitNOFILE: "infinity",
+			LimitNPROC:  "4096",
+			Restart:     "always",
+			RestartSec:  "5s",
+			After:       []string{"network-online.target"},
+		}},
+		{name: "Multi-line value", service: &Service{LimitNOFILE: "1\nExecStart=/bin/sh"}, wantErr: "service: limitNOFILE spans multiple lines"},
+		{name: "Invalid limitNOFILE", service: &Service{LimitNOFILE: "unlimited"}, wantErr: `service: limitNOFILE must be a number or infinity, got "unlimited"`},
+		{name: "Negative limitNPROC", service: &Service{LimitNPROC: "-1"}, wantErr: `service: limitNPROC must be a number or infinity, got "-1"`},
+		{name: "Shell in limitNPROC", service: &Service{LimitNPROC: "1; reboot"}, wantErr: `service: limitNPROC must be a number or infinity, got "1; reboot"`},
+		{name: "Invalid restart", service: &Service{Restart: "sometimes"}, wantErr: `service: invalid restart "sometimes"`},
+		{name: "Invalid unit", service: &Service{After: []string{"a b"}}, wantErr: `service: invalid unit "a b" in after`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateService(tt.service)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateService() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateService() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Swap policies.
+const (
+	// SwapDisable comments out swap in /etc/fstab and turns it off. It is the default.
+	SwapDisable = "disable"
+	// SwapIgnore leaves swap as the host configures it.
+	SwapIgnore = "ignore"
+	// SwapAllow leaves swap on and lets the kubelet run with it.
+	SwapAllow = "allow"
+)
+
+// Kubelet swap behaviors, see https://kubernetes.io/docs/concepts/cluster-administration/swap-memory-management/.
+const (
+	LimitedSwap = "LimitedSwap"
+	NoSwap      = "NoSwap"
+)
+
+var (
+	SwapPolicies  = []string{SwapDisable, SwapIgnore, SwapAllow}
+	SwapBehaviors = []string{LimitedSwap, NoSwap}
+)
+
+// Swap selects how swap is handled on the node.
+type Swap struct {
+	Policy string `yaml:"policy,omitempty" json:"policy,omitempty"`
+	// SwapBehavior is the kubelet memorySwap.swapBehavior with the allow policy, LimitedSwap by default.
+	SwapBehavior string `yaml:"swapBehavior,omitempty" json:"swapBehavior,omitempty"`
+}
+
+// SwapPolicy returns the configured policy, or SwapDisable.
+func (s *Swap) SwapPolicy() string {
+	if s == nil || s.Policy == "" {
+		return SwapDisable
+	}
+	return s.Policy
+}
+
+// ValidateSwap checks the policy and the swap behavior.
+func ValidateSwap(s *Swap) error {
+	if s == nil {
+		return nil
+	}
+	if !slices.Contains(SwapPolicies, s.SwapPolicy()) {
+		return fmt.Errorf("swap: invalid policy %q, expected one of %s", s.Policy, strings.Join(SwapPolicies, ", "))
+	}
+	if s.SwapBehavior == "" {
+		return nil
+	}
+	if s.SwapPolicy() != SwapAllow {
+		return fmt.Errorf("swap: swapBehavior requires the %s policy", SwapAllow)
+	}
+	if !slices.Contains(SwapBehaviors, s.SwapBehavior) {
+		return fmt.Errorf("swap: invalid swapBehavior %q, expected one of %s", s.SwapBehavior, strings.Join(SwapBehaviors, ", "))
+	}
+	return nil
+}
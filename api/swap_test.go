@@ -0,0 +1,35 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_ValidateSwap(t *testing.T) {
+	tests := []struct {
+		name    string
+		swap    *Swap
+		wantErr string
+	}{
+		{name: "No section"},
+		{name: "Allow with behavior", swap: &Swap{Policy: SwapAllow, SwapBehavior: NoSwap}},
+		{name: "Unknown policy", swap: &Swap{Policy: "off"}, wantErr: `swap: invalid policy "off"`},
+		{name: "Unknown behavior", swap: &Swap{Policy: SwapAllow, SwapBehavior: "UnlimitedSwap"}, wantErr: `swap: invalid swapBehavior "UnlimitedSwap"`},
+		{name: "Behavior without allow", swap: &Swap{SwapBehavior: LimitedSwap}, wantErr: "swap: swapBehavior requires the allow policy"},
+		{name: "Behavior with ignore", swap: &Swap{Policy: SwapIgnore, SwapBehavior: NoSwap}, wantErr: "swap: swapBehavior requires the allow policy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSwap(tt.swap)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateSwap() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateSwap() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,15 +1,29 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/kairos-io/kairos-sdk/clusterplugin"
 	"github.com/mudler/go-pluggable"
 	"github.com/sirupsen/logrus"
 
+	"github.com/kairos-io/provider-k3s/pkg/cmd"
 	"github.com/kairos-io/provider-k3s/pkg/log"
 	"github.com/kairos-io/provider-k3s/pkg/provider"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := cmd.Lookup(os.Args[1]); ok {
+			if err := command.Run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", command.Name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	log.InitLogger("/var/log/provider-k3s.log")
 
 	plugin := clusterplugin.ClusterPlugin{
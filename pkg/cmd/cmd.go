@@ -0,0 +1,25 @@
+package cmd
+
+// Command is a subcommand of the provider binary. Subcommands run instead of the plugin event loop, so they can be
+// called from yip stages and used offline without a Kairos agent.
+type Command struct {
+	Name  string
+	Usage string
+	Run   func(args []string) error
+}
+
+var commands []Command
+
+func register(c Command) {
+	commands = append(commands, c)
+}
+
+// Lookup returns the subcommand registered under name.
+func Lookup(name string) (Command, bool) {
+	for _, c := range commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Command{}, false
+}
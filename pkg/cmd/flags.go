@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// stringsFlag collects repeated flags into a list.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// keyValueFlag collects repeated key=value flags into a map.
+type keyValueFlag map[string]string
+
+func (f keyValueFlag) String() string {
+	var pairs []string
+	for k, v := range f {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, v))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f keyValueFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	f[k] = v
+	return nil
+}
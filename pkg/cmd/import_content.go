@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+	"github.com/kairos-io/provider-k3s/pkg/content"
+)
+
+func init() {
+	register(Command{
+		Name:  constants.ImportContentCommand,
+		Usage: "install the charts and manifests of a content bundle into the k3s data-dir",
+		Run:   runImportContent,
+	})
+}
+
+func runImportContent(args []string) error {
+	fs := flag.NewFlagSet(constants.ImportContentCommand, flag.ContinueOnError)
+	path := fs.String("path", "", "root of the content bundle")
+	dataDir := fs.String("data-dir", constants.K3sDataDir, "k3s data-dir to install into")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return fmt.Errorf("--path is required")
+	}
+
+	importer, err := content.NewImporter(*path)
+	if err != nil {
+		return err
+	}
+	if importer.Checksums == nil {
+		fmt.Fprintf(os.Stdout, "warning: %s has no %s, artifacts are not verified\n", *path, content.ChecksumsFile)
+	}
+
+	charts, chartsErr := importer.ImportCharts(filepath.Join(*dataDir, constants.K3sStaticChartsDir))
+	report("chart", charts)
+	manifests, manifestsErr := importer.ImportManifests(filepath.Join(*dataDir, constants.K3sManifestsDir))
+	report("manifest", manifests)
+
+	return errors.Join(chartsErr, manifestsErr)
+}
+
+func report(kind string, result content.Result) {
+	for _, f := range result.Installed {
+		fmt.Fprintf(os.Stdout, "installed %s %s\n", kind, f)
+	}
+	for _, f := range result.Failed {
+		fmt.Fprintf(os.Stdout, "skipped %s %s\n", kind, f)
+	}
+}
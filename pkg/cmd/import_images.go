@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+	"github.com/kairos-io/provider-k3s/pkg/content"
+)
+
+func init() {
+	register(Command{
+		Name:  constants.ImportImagesCommand,
+		Usage: "import the image archives of a content bundle into the k3s images directory",
+		Run:   runImportImages,
+	})
+}
+
+func runImportImages(args []string) error {
+	fs := flag.NewFlagSet(constants.ImportImagesCommand, flag.ContinueOnError)
+	path := fs.String("path", "", "root of the content bundle")
+	imagesDir := fs.String("images-dir", filepath.Join(constants.K3sDataDir, constants.K3sImagesDir), "k3s images directory to import into")
+	summaryPath := fs.String("summary", filepath.Join(constants.RunDir, "import-images.json"), "file to write the JSON import summary to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return fmt.Errorf("--path is required")
+	}
+
+	importer, err := content.NewImporter(*path)
+	if err != nil {
+		return err
+	}
+	if importer.Checksums == nil {
+		fmt.Fprintf(os.Stdout, "warning: %s has no %s, images are not verified\n", *path, content.ChecksumsFile)
+	}
+
+	summary, importErr := importer.ImportImages(*imagesDir)
+	for _, i := range summary.Imported {
+		fmt.Fprintf(os.Stdout, "imported %s as %s (sha256 %s)\n", i.Source, i.File, i.SHA256)
+	}
+	for _, i := range summary.Unchanged {
+		fmt.Fprintf(os.Stdout, "unchanged %s (sha256 %s)\n", i.Source, i.SHA256)
+	}
+	for _, i := range summary.Duplicates {
+		fmt.Fprintf(os.Stdout, "skipped duplicate %s of %s\n", i.Source, i.File)
+	}
+	for _, i := range summary.Failed {
+		fmt.Fprintf(os.Stdout, "failed %s: %s\n", i.Source, i.Error)
+	}
+
+	return errors.Join(importErr, content.WriteSummary(*summaryPath, summary))
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"flag"
+
+	"github.com/kairos-io/provider-k3s/pkg/config"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+func init() {
+	register(Command{
+		Name:  constants.MergeConfigCommand,
+		Usage: "merge the k3s config.d drop-ins into config.yaml",
+		Run:   runMergeConfig,
+	})
+}
+
+func runMergeConfig(args []string) error {
+	fs := flag.NewFlagSet(constants.MergeConfigCommand, flag.ContinueOnError)
+	dir := fs.String("dir", constants.K3sConfigDir, "directory holding the *.yaml drop-ins to merge")
+	output := fs.String("output", constants.K3sConfigFile, "file to write the merged config to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	merged, err := config.MergeDir(*dir)
+	if err != nil {
+		return err
+	}
+	return config.WriteFile(*output, merged, 0600)
+}
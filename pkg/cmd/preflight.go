@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+	"github.com/kairos-io/provider-k3s/pkg/preflight"
+	"github.com/kairos-io/provider-k3s/pkg/version"
+)
+
+func init() {
+	register(Command{
+		Name:  constants.PreflightCommand,
+		Usage: "check the host before k3s is started and write a JSON report",
+		Run:   runPreflight,
+	})
+}
+
+func runPreflight(args []string) error {
+	fs := flag.NewFlagSet(constants.PreflightCommand, flag.ContinueOnError)
+	role := fs.String("role", "", "node role the checks are run for")
+	dataDir := fs.String("data-dir", constants.K3sDataDir, "k3s data-dir whose filesystem is checked")
+	ports := fs.String("ports", "", "comma separated TCP ports k3s needs to bind")
+	skip := fs.String("skip", "", "comma separated checks to skip")
+	output := fs.String("output", constants.PreflightReport, "file to write the JSON report to")
+	block := fs.Bool("block", false, "fail and mark the run failed when a check fails")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := preflight.Options{
+		Role:    *role,
+		DataDir: *dataDir,
+		Skip:    splitList(*skip),
+	}
+	for _, p := range splitList(*ports) {
+		port, err := strconv.Atoi(p)
+		if err != nil {
+			return fmt.Errorf("invalid port %q", p)
+		}
+		opts.Ports = append(opts.Ports, port)
+	}
+	// The modification time of the binary is often reset by reproducible builds and image layers, so the build time
+	// comes from the ldflags.
+	buildDate, _ := time.Parse(time.RFC3339, version.BuildDate)
+	opts.ClockFloor = preflight.ClockFloor(buildDate, *dataDir)
+
+	report := preflight.Run(opts)
+	for _, r := range report.Results {
+		fmt.Fprintf(os.Stdout, "%s %s: %s\n", r.Status, r.Name, r.Message)
+	}
+	if err := preflight.WriteReport(*output, report); err != nil {
+		return err
+	}
+
+	if !*block || report.Passed {
+		if err := os.Remove(constants.PreflightFailed); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.WriteFile(constants.PreflightFailed, nil, 0600); err != nil {
+		return err
+	}
+	return fmt.Errorf("pre-flight checks failed, k3s will not be started; see %s", *output)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
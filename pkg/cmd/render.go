@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	yip "github.com/mudler/yip/pkg/schema"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/pkg/config"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+	"github.com/kairos-io/provider-k3s/pkg/provider"
+)
+
+func init() {
+	register(Command{
+		Name:  constants.RenderCommand,
+		Usage: "print the yip config generated for a cloud-config without booting a node",
+		Run:   runRender,
+	})
+}
+
+func runRender(args []string) error {
+	env := keyValueFlag{}
+	providerOptions := keyValueFlag{}
+	nodeAddresses := stringsFlag{}
+
+	fs := flag.NewFlagSet(constants.RenderCommand, flag.ContinueOnError)
+	cloudConfig := fs.String("config", "", "cloud-config file holding the cluster section, - for stdin")
+	role := fs.String("role", "", "override cluster.role")
+	fs.Var(env, "env", "set a cluster.env entry as KEY=VALUE, may be repeated")
+	fs.Var(providerOptions, "provider-option", "set a cluster.providerConfig entry as key=value, may be repeated")
+	binary := fs.String("provider-binary", constants.ProviderBinary, "plugin binary path stages call back into")
+	fs.Var(&nodeAddresses, "node-address", "add a node address, as an IP or CIDR, to the default NO_PROXY instead of the addresses of this host, may be repeated")
+	hostState := fs.Bool("host-state", false, "check against the k3s state under the data dir of this host, when rendering on the node itself")
+	merged := fs.Bool("merged", false, "print the config.yaml the generated drop-ins merge into instead of the yip config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *cloudConfig == "" {
+		return fmt.Errorf("--config is required")
+	}
+
+	cluster, err := readCluster(*cloudConfig)
+	if err != nil {
+		return err
+	}
+	if *role != "" {
+		cluster.Role = clusterplugin.Role(*role)
+	}
+	for _, address := range nodeAddresses {
+		if net.ParseIP(address) == nil {
+			if _, _, err := net.ParseCIDR(address); err != nil {
+				return fmt.Errorf("invalid --node-address %q", address)
+			}
+		}
+	}
+	cluster.Env = mergeMap(cluster.Env, env)
+	cluster.ProviderOptions = mergeMap(cluster.ProviderOptions, providerOptions)
+
+	logrus.SetLevel(logrus.WarnLevel)
+
+	// The rendering host is not the node, so its interfaces and data dir say nothing about the node.
+	cfg, err := provider.BuildConfig(cluster, provider.RenderOptions{
+		ProviderBinary: *binary,
+		NodeAddresses:  append([]string{}, nodeAddresses...),
+		SkipHostState:  !*hostState,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *merged {
+		mergedConfig, err := mergeGeneratedConfig(cfg)
+		if err != nil {
+			return err
+		}
+		return yaml.NewEncoder(os.Stdout).Encode(mergedConfig)
+	}
+
+	if _, err := fmt.Fprintln(os.Stdout, "#cloud-config"); err != nil {
+		return err
+	}
+	return yaml.NewEncoder(os.Stdout).Encode(cfg)
+}
+
+// readCluster loads the cluster section of a cloud-config file.
+func readCluster(path string) (clusterplugin.Cluster, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return clusterplugin.Cluster{}, err
+	}
+
+	var cc clusterplugin.Config
+	if err := yaml.Unmarshal(data, &cc); err != nil {
+		return clusterplugin.Cluster{}, fmt.Errorf("failed to parse cloud-config %s: %w", path, err)
+	}
+	if cc.Cluster == nil {
+		return clusterplugin.Cluster{}, fmt.Errorf("cloud-config %s has no cluster section", path)
+	}
+	return *cc.Cluster, nil
+}
+
+// mergeGeneratedConfig merges the config.d drop-ins written by cfg the same way the merge-config stage does on a node.
+// Drop-ins that only exist on the node are not part of the result.
+func mergeGeneratedConfig(cfg yip.YipConfig) (map[string]interface{}, error) {
+	var files []yip.File
+	for _, stages := range cfg.Stages {
+		for _, stage := range stages {
+			for _, f := range stage.Files {
+				if filepath.Dir(f.Path) == constants.K3sConfigDir {
+					files = append(files, f)
+				}
+			}
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	var docs []map[string]interface{}
+	for _, f := range files {
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal([]byte(f.Content), &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse generated %s: %w", f.Path, err)
+		}
+		docs = append(docs, doc)
+	}
+	return config.Merge(docs...), nil
+}
+
+func mergeMap(base, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return base
+	}
+	out := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range overrides {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kairos-io/provider-k3s/pkg/provider"
+)
+
+func Test_renderMergedConfig(t *testing.T) {
+	cloudConfig := filepath.Join(t.TempDir(), "cloud-config.yaml")
+	content := `#cloud-config
+cluster:
+  cluster_token: token
+  control_plane_host: localhost
+  role: worker
+  config: |
+    node-label: a=b
+    cluster-cidr: 10.42.0.0/16
+    service-cidr: 10.43.0.0/16
+`
+	if err := os.WriteFile(cloudConfig, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cluster, err := readCluster(cloudConfig)
+	if err != nil {
+		t.Fatalf("readCluster() error = %v", err)
+	}
+	cluster.Role = "init"
+	cluster.ProviderOptions = mergeMap(cluster.ProviderOptions, keyValueFlag{"cluster-init": "no"})
+
+	cfg, err := provider.BuildConfig(cluster, provider.RenderOptions{})
+	if err != nil {
+		t.Fatalf("BuildConfig() error = %v", err)
+	}
+	got, err := mergeGeneratedConfig(cfg)
+	if err != nil {
+		t.Fatalf("mergeGeneratedConfig() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"cluster-cidr": []interface{}{"10.42.0.0/16"},
+		"cluster-init": false,
+		"node-label":   []interface{}{"a=b"},
+		"service-cidr": []interface{}{"10.43.0.0/16"},
+		"tls-san":      []interface{}{"localhost"},
+		"token":        "token",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeGeneratedConfig() = %v, want %v", got, want)
+	}
+}
+
+func Test_renderIgnoresHostState(t *testing.T) {
+	// The data dir of the rendering host still uses a key the cloud-config does not list.
+	dataDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dataDir, "server"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	current := `kind: EncryptionConfiguration
+apiVersion: apiserver.config.k8s.io/v1
+resources:
+  - resources: [secrets]
+    providers:
+      - aescbc:
+          keys:
+            - name: key1
+              secret: c2VjcmV0LWtleS0xLXRoaXJ0eS10d28tYnl0ZXMhISE=
+      - identity: {}
+`
+	if err := os.WriteFile(filepath.Join(dataDir, "server/encryption.yaml"), []byte(current), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cloudConfig := filepath.Join(t.TempDir(), "cloud-config.yaml")
+	content := `#cloud-config
+cluster:
+  cluster_token: token
+  role: init
+  env:
+    HTTP_PROXY: http://proxy:3128
+  config: |
+    data-dir: ` + dataDir + `
+`
+	if err := os.WriteFile(cloudConfig, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	err = runRender([]string{"--config", cloudConfig, "--node-address", "192.168.1.10"})
+	os.Stdout = stdout
+	w.Close()
+	out, _ := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("runRender() error = %v", err)
+	}
+
+	if want := "NO_PROXY=10.42.0.0/16,10.43.0.0/16,192.168.1.10,.svc"; !strings.Contains(string(out), want) {
+		t.Errorf("rendered config does not contain %q:\n%s", want, out)
+	}
+
+	if err := runRender([]string{"--config", cloudConfig, "--host-state"}); err == nil || !strings.Contains(err.Error(), "the section was removed") {
+		t.Errorf("runRender() with --host-state error = %v, want the encryption check", err)
+	}
+	if err := runRender([]string{"--config", cloudConfig, "--node-address", "node"}); err == nil {
+		t.Errorf("runRender() expected an error for an invalid node address")
+	}
+}
+
+func Test_readClusterWithoutClusterSection(t *testing.T) {
+	cloudConfig := filepath.Join(t.TempDir(), "cloud-config.yaml")
+	if err := os.WriteFile(cloudConfig, []byte("#cloud-config\nhostname: node\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readCluster(cloudConfig); err == nil {
+		t.Errorf("readCluster() expected an error")
+	}
+}
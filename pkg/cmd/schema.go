@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+	"github.com/kairos-io/provider-k3s/pkg/jsonschema"
+)
+
+func init() {
+	register(Command{
+		Name:  constants.SchemaCommand,
+		Usage: "print the JSON Schema of the cluster config section",
+		Run:   runSchema,
+	})
+}
+
+func runSchema(args []string) error {
+	fs := flag.NewFlagSet(constants.SchemaCommand, flag.ContinueOnError)
+	role := fs.String("role", string(jsonschema.VariantAll), "schema variant: server, agent, all, or a node role")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	variant, err := jsonschema.ParseVariant(*role)
+	if err != nil {
+		return err
+	}
+	s, err := jsonschema.Generate(variant)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(s)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+	"github.com/kairos-io/provider-k3s/pkg/validation"
+)
+
+func init() {
+	register(Command{
+		Name:  constants.ValidateCommand,
+		Usage: "check the cluster config section of a cloud-config for unknown and mistyped keys",
+		Run:   runValidate,
+	})
+}
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet(constants.ValidateCommand, flag.ContinueOnError)
+	cloudConfig := fs.String("config", "", "cloud-config file holding the cluster section, - for stdin")
+	role := fs.String("role", "", "override cluster.role")
+	strict := fs.Bool("strict", false, "fail on warnings as well as errors")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *cloudConfig == "" {
+		return fmt.Errorf("--config is required")
+	}
+
+	cluster, err := readCluster(*cloudConfig)
+	if err != nil {
+		return err
+	}
+	if *role != "" {
+		cluster.Role = clusterplugin.Role(*role)
+	}
+
+	issues, err := validation.Validate(cluster)
+	if err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		fmt.Fprintln(os.Stdout, issue)
+	}
+
+	if validation.HasErrors(issues) || (*strict && len(issues) > 0) {
+		return fmt.Errorf("found %d problem(s) in %s", len(issues), *cloudConfig)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AppendSuffix marks a key whose value is appended to the value of the same key from earlier files, as k3s does for
+// its own config.yaml.d drop-ins.
+const AppendSuffix = "+"
+
+// Merge combines config documents in order. Later scalars override earlier ones, lists are appended, and keys with
+// the AppendSuffix always append to the existing value, turning a scalar into a list if needed.
+func Merge(docs ...map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for _, doc := range docs {
+		mergeInto(out, doc)
+	}
+	return out
+}
+
+func mergeInto(out, doc map[string]interface{}) {
+	var plain, appends []string
+	for k := range doc {
+		if strings.HasSuffix(k, AppendSuffix) {
+			appends = append(appends, k)
+		} else {
+			plain = append(plain, k)
+		}
+	}
+	sort.Strings(plain)
+	sort.Strings(appends)
+
+	// Plain keys go first so an append key always extends the value set by the same document.
+	for _, k := range plain {
+		v := doc[k]
+		if v == nil {
+			continue
+		}
+		existing, ok := out[k].([]interface{})
+		if list, isList := toList(v); isList && ok {
+			out[k] = append(existing, list...)
+			continue
+		}
+		out[k] = normalize(v)
+	}
+
+	for _, k := range appends {
+		v := doc[k]
+		if v == nil {
+			continue
+		}
+		key := strings.TrimSuffix(k, AppendSuffix)
+		var merged []interface{}
+		if existing, ok := out[key]; ok {
+			merged = asList(existing)
+		}
+		out[key] = append(merged, asList(v)...)
+	}
+}
+
+// MergeFiles reads and merges the given YAML (or JSON) files in the order they are passed.
+func MergeFiles(paths ...string) (map[string]interface{}, error) {
+	var docs []map[string]interface{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		docs = append(docs, doc)
+	}
+	return Merge(docs...), nil
+}
+
+// MergeDir merges every *.yaml file in dir in lexical file name order.
+func MergeDir(dir string) (map[string]interface{}, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return MergeFiles(paths...)
+}
+
+// WriteFile renders the merged config to path, replacing it atomically.
+func WriteFile(path string, cfg map[string]interface{}, perm os.FileMode) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func toList(v interface{}) ([]interface{}, bool) {
+	switch v := v.(type) {
+	case []interface{}:
+		return v, true
+	case []string:
+		out := make([]interface{}, 0, len(v))
+		for _, s := range v {
+			out = append(out, s)
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func asList(v interface{}) []interface{} {
+	if list, ok := toList(v); ok {
+		return append([]interface{}{}, list...)
+	}
+	return []interface{}{v}
+}
+
+func normalize(v interface{}) interface{} {
+	if list, ok := toList(v); ok {
+		return append([]interface{}{}, list...)
+	}
+	return v
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_Merge(t *testing.T) {
+	tests := []struct {
+		name string
+		docs []map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "Scalars override in order",
+			docs: []map[string]interface{}{
+				{"token": "first", "cluster-init": true},
+				{"token": "second", "cluster-init": false},
+			},
+			want: map[string]interface{}{"token": "second", "cluster-init": false},
+		},
+		{
+			name: "Lists append",
+			docs: []map[string]interface{}{
+				{"tls-san": []interface{}{"a"}},
+				{"tls-san": []interface{}{"b", "c"}},
+			},
+			want: map[string]interface{}{"tls-san": []interface{}{"a", "b", "c"}},
+		},
+		{
+			name: "Scalar replaces list",
+			docs: []map[string]interface{}{
+				{"node-name": []interface{}{"a"}},
+				{"node-name": "b"},
+			},
+			want: map[string]interface{}{"node-name": "b"},
+		},
+		{
+			name: "Append key extends earlier files",
+			docs: []map[string]interface{}{
+				{"node-label": []interface{}{"a=1"}},
+				{"node-label+": []interface{}{"b=2"}},
+			},
+			want: map[string]interface{}{"node-label": []interface{}{"a=1", "b=2"}},
+		},
+		{
+			name: "Append key applies after plain key of the same file",
+			docs: []map[string]interface{}{
+				{"node-label+": "b=2", "node-label": "a=1"},
+			},
+			want: map[string]interface{}{"node-label": []interface{}{"a=1", "b=2"}},
+		},
+		{
+			name: "Append key without base value",
+			docs: []map[string]interface{}{
+				{"kubelet-arg+": "max-pods=200"},
+			},
+			want: map[string]interface{}{"kubelet-arg": []interface{}{"max-pods=200"}},
+		},
+		{
+			name: "Null values are ignored",
+			docs: []map[string]interface{}{
+				{"token": "first"},
+				{"token": nil},
+			},
+			want: map[string]interface{}{"token": "first"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Merge(tt.docs...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_MergeDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"90_userdata.yaml": `{"tls-san":["user"],"token":"user"}`,
+		"99_userdata.yaml": `{"tls-san":["localhost"],"token":"token","cluster-init":true}`,
+		"50_custom.yaml":   "node-label:\n  - custom=true\n",
+		"ignored.yml":      "token: ignored\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := MergeDir(dir)
+	if err != nil {
+		t.Fatalf("MergeDir() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"node-label":   []interface{}{"custom=true"},
+		"tls-san":      []interface{}{"user", "localhost"},
+		"token":        "token",
+		"cluster-init": true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeDir() = %v, want %v", got, want)
+	}
+
+	output := filepath.Join(dir, "config.yaml")
+	if err := WriteFile(output, got, 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	info, err := os.Stat(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("WriteFile() mode = %v, want 0600", info.Mode().Perm())
+	}
+}
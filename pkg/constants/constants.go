@@ -5,6 +5,14 @@ const (
 	EnableSystemdServices = "Enable Systemd Services"
 	InstallK3sConfigFiles = "Install K3s Configuration Files"
 	ImportK3sImages       = "Import K3s Images"
+	K3sConfigError        = "K3s Configuration Error"
+	InstallK3sManifests   = "Install K3s Manifests"
+	ImportK3sContent      = "Import K3s Content"
+	InstallSystemdDropIns = "Install K3s Systemd Overrides"
+	InstallOpenRCConf     = "Install K3s OpenRC Settings"
+	RunK3sPreflight       = "Run K3s Preflight Checks"
+	LoadKernelModules     = "Load K3s Kernel Modules"
+	ApplySysctls          = "Apply K3s Sysctls"
 )
 
 // The following are keys provider-k3s supports if present in Cluster.ProviderOptions from the Kairos SDK.
@@ -28,9 +36,70 @@ const (
 
 	// TLS key file used for client certificate based authentication to your datastore.
 	DatastoreKeyFile string = "datastore-keyfile"
+
+	// Port the k3s API is served on. Join URLs use it unless the control plane host names a port.
+	HTTPSListenPort string = "https-listen-port"
+)
+
+const (
+	K3sConfigDir  = "/etc/rancher/k3s/config.d"
+	K3sConfigFile = "/etc/rancher/k3s/config.yaml"
+
+	// K3sDataDir is the default k3s data-dir; the manifests and charts directories are relative to it.
+	K3sDataDir         = "/var/lib/rancher/k3s"
+	K3sManifestsDir    = "server/manifests"
+	K3sStaticChartsDir = "server/static/charts"
+	K3sImagesDir       = "agent/images"
+
+	// K3sPSAConfigFile, K3sAuditPolicyFile, K3sAuditLogFile, K3sEncryptionConfigFile and the OIDC files are the
+	// API server hardening and authentication files, relative to K3sDataDir.
+	K3sPSAConfigFile            = "server/psa.yaml"
+	K3sAuditPolicyFile          = "server/audit.yaml"
+	K3sAuditLogFile             = "server/logs/audit.log"
+	K3sEncryptionConfigFile     = "server/encryption.yaml"
+	K3sOIDCCAFile               = "server/tls/oidc-ca.crt"
+	K3sAuthenticationConfigFile = "server/authentication.yaml"
+
+	// K3sManagedEncryptionConfigFile holds the keys k3s generates for secrets-encryption, relative to K3sDataDir.
+	K3sManagedEncryptionConfigFile = "server/cred/encryption-config.json"
+
+	// K3sKubeletConfigDir is the kubelet config drop-in directory k3s passes to the kubelet, relative to K3sDataDir.
+	K3sKubeletConfigDir = "agent/etc/kubelet.conf.d"
+
+	// RunDir holds reports and settings the provider writes for the current boot.
+	RunDir = "/run/provider-k3s"
+
+	// PreflightReport is the pre-flight report, and PreflightFailed exists while a blocking pre-flight run has failed.
+	PreflightReport = RunDir + "/preflight.json"
+	PreflightFailed = RunDir + "/preflight.failed"
+
+	// ProviderBinary is where Kairos images install the plugin binary.
+	ProviderBinary = "/system/providers/agent-provider-k3s"
+
+	// MergeConfigCommand is the provider subcommand that merges K3sConfigDir into K3sConfigFile.
+	MergeConfigCommand = "merge-config"
+
+	// RenderCommand is the provider subcommand that prints the generated yip config for a cloud-config.
+	RenderCommand = "render"
+
+	// ValidateCommand is the provider subcommand that checks the cluster config section of a cloud-config.
+	ValidateCommand = "validate"
+
+	// SchemaCommand is the provider subcommand that prints the JSON Schema of the cluster config section.
+	SchemaCommand = "schema"
+
+	// ImportContentCommand is the provider subcommand that installs the charts and manifests of a content bundle.
+	ImportContentCommand = "import-content"
+
+	// ImportImagesCommand is the provider subcommand that imports the image archives of a content bundle.
+	ImportImagesCommand = "import-images"
+
+	// PreflightCommand is the provider subcommand that checks the host before k3s is started.
+	PreflightCommand = "preflight"
 )
 
 const (
-	ClusterRootPath         = "cluster_root_path"
+	ClusterRootPath     = "cluster_root_path"
+	NoProxyInterfaces   = "no_proxy_interfaces"
 	RunSystemdSystemDir = "/run/systemd/system"
 )
@@ -0,0 +1,89 @@
+package content
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumsFile lists the SHA256 of the bundle artifacts in sha256sum format, with paths relative to the bundle root.
+const ChecksumsFile = "SHA256SUMS"
+
+// Checksums maps bundle-relative paths to their expected SHA256. A nil Checksums skips verification.
+type Checksums map[string]string
+
+// LoadChecksums reads the ChecksumsFile of root. It returns nil without error when the bundle has none.
+func LoadChecksums(root string) (Checksums, error) {
+	f, err := os.Open(filepath.Join(root, ChecksumsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sums := make(Checksums)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		sum, path, ok := strings.Cut(text, " ")
+		if !ok || len(sum) != sha256.Size*2 {
+			return nil, fmt.Errorf("%s:%d: expected '<sha256>  <path>'", ChecksumsFile, line)
+		}
+		// sha256sum marks binary mode with a leading '*'.
+		path = strings.TrimPrefix(strings.TrimSpace(path), "*")
+		sums[filepath.Clean(path)] = strings.ToLower(sum)
+	}
+	return sums, scanner.Err()
+}
+
+// Verify checks the artifact at root/rel against its listed checksum. Artifacts missing from the list fail.
+func (c Checksums) Verify(root, rel string) error {
+	if c == nil {
+		return nil
+	}
+	got, err := FileSHA256(filepath.Join(root, rel))
+	if err != nil {
+		return err
+	}
+	return c.VerifyDigest(rel, got)
+}
+
+// VerifyDigest checks an already computed digest of the artifact rel against its listed checksum.
+func (c Checksums) VerifyDigest(rel, digest string) error {
+	if c == nil {
+		return nil
+	}
+	want, ok := c[filepath.Clean(rel)]
+	if !ok {
+		return fmt.Errorf("%s is not listed in %s", rel, ChecksumsFile)
+	}
+	if digest != want {
+		return fmt.Errorf("%s: checksum mismatch, got %s, want %s", rel, digest, want)
+	}
+	return nil
+}
+
+// FileSHA256 returns the hex encoded SHA256 of the file at path.
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
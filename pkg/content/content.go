@@ -0,0 +1,140 @@
+package content
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A content bundle holds one directory per artifact kind. Bundles without these directories are treated as a flat
+// directory of images, as before.
+const (
+	ImagesDir    = "images"
+	ChartsDir    = "charts"
+	ManifestsDir = "manifests"
+)
+
+var (
+	chartSuffixes    = []string{".tgz", ".tar.gz"}
+	manifestSuffixes = []string{".yaml", ".yml", ".json"}
+)
+
+// Result lists the files installed and skipped by an import.
+type Result struct {
+	Installed []string
+	Failed    []string
+}
+
+// Importer installs bundle artifacts into the k3s data-dir.
+type Importer struct {
+	Root      string
+	Checksums Checksums
+}
+
+// NewImporter loads the checksums of the bundle at root.
+func NewImporter(root string) (*Importer, error) {
+	sums, err := LoadChecksums(root)
+	if err != nil {
+		return nil, err
+	}
+	return &Importer{Root: root, Checksums: sums}, nil
+}
+
+// ImportCharts copies chart tarballs from the charts directory into dest, where the k3s helm controller serves them.
+func (i *Importer) ImportCharts(dest string) (Result, error) {
+	return i.importDir(ChartsDir, chartSuffixes, dest)
+}
+
+// ImportManifests copies manifests from the manifests directory into the k3s auto-deploy directory dest.
+func (i *Importer) ImportManifests(dest string) (Result, error) {
+	return i.importDir(ManifestsDir, manifestSuffixes, dest)
+}
+
+// importDir verifies and installs the files of a bundle directory. A file that fails verification is skipped and
+// reported, the others are still installed.
+func (i *Importer) importDir(dir string, suffixes []string, dest string) (Result, error) {
+	var result Result
+
+	entries, err := os.ReadDir(filepath.Join(i.Root, dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].Name() < entries[b].Name() })
+
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !hasSuffix(entry.Name(), suffixes) {
+			continue
+		}
+		rel := filepath.Join(dir, entry.Name())
+		if err := i.Checksums.Verify(i.Root, rel); err != nil {
+			result.Failed = append(result.Failed, rel)
+			errs = append(errs, err)
+			continue
+		}
+		if err := CopyFile(filepath.Join(i.Root, rel), filepath.Join(dest, entry.Name()), 0600); err != nil {
+			result.Failed = append(result.Failed, rel)
+			errs = append(errs, err)
+			continue
+		}
+		result.Installed = append(result.Installed, rel)
+	}
+	return result, errors.Join(errs...)
+}
+
+// HasLayout reports whether root uses the images/charts/manifests bundle layout.
+func HasLayout(root string) bool {
+	for _, dir := range []string{ImagesDir, ChartsDir, ManifestsDir} {
+		if info, err := os.Stat(filepath.Join(root, dir)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// CopyFile copies src to dst through a temporary file, so k3s never picks up a partial file.
+func CopyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func hasSuffix(name string, suffixes []string) bool {
+	for _, s := range suffixes {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package content
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeBundle(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for rel, data := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func sum(data string) string {
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
+
+func Test_Importer(t *testing.T) {
+	files := map[string]string{
+		"charts/app-1.0.0.tgz":    "chart",
+		"charts/README.md":        "ignored",
+		"manifests/app.yaml":      "kind: ConfigMap",
+		"manifests/tampered.yaml": "kind: Secret",
+		"manifests/unlisted.yaml": "kind: Secret",
+		"images/app.tar":          "image",
+	}
+	checksums := strings.Join([]string{
+		fmt.Sprintf("%s  charts/app-1.0.0.tgz", sum("chart")),
+		fmt.Sprintf("%s *manifests/app.yaml", sum("kind: ConfigMap")),
+		fmt.Sprintf("%s  manifests/tampered.yaml", sum("kind: Pod")),
+	}, "\n")
+	files[ChecksumsFile] = checksums
+	root := writeBundle(t, files)
+
+	if !HasLayout(root) {
+		t.Fatalf("HasLayout() = false")
+	}
+
+	importer, err := NewImporter(root)
+	if err != nil {
+		t.Fatalf("NewImporter() error = %v", err)
+	}
+	dest := t.TempDir()
+
+	charts, err := importer.ImportCharts(filepath.Join(dest, "charts"))
+	if err != nil {
+		t.Fatalf("ImportCharts() error = %v", err)
+	}
+	if want := []string{"charts/app-1.0.0.tgz"}; !reflect.DeepEqual(charts.Installed, want) {
+		t.Errorf("ImportCharts() installed %v, want %v", charts.Installed, want)
+	}
+
+	manifests, err := importer.ImportManifests(filepath.Join(dest, "manifests"))
+	if err == nil {
+		t.Errorf("ImportManifests() expected an error for tampered and unlisted files")
+	}
+	if want := []string{"manifests/app.yaml"}; !reflect.DeepEqual(manifests.Installed, want) {
+		t.Errorf("ImportManifests() installed %v, want %v", manifests.Installed, want)
+	}
+	if want := []string{"manifests/tampered.yaml", "manifests/unlisted.yaml"}; !reflect.DeepEqual(manifests.Failed, want) {
+		t.Errorf("ImportManifests() failed %v, want %v", manifests.Failed, want)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "manifests", "tampered.yaml")); !os.IsNotExist(err) {
+		t.Errorf("tampered manifest was installed")
+	}
+}
+
+func Test_ImporterWithoutChecksums(t *testing.T) {
+	root := writeBundle(t, map[string]string{"manifests/app.yml": "kind: ConfigMap"})
+	importer, err := NewImporter(root)
+	if err != nil {
+		t.Fatalf("NewImporter() error = %v", err)
+	}
+	if importer.Checksums != nil {
+		t.Errorf("NewImporter() loaded checksums from a bundle without %s", ChecksumsFile)
+	}
+
+	dest := t.TempDir()
+	result, err := importer.ImportManifests(dest)
+	if err != nil {
+		t.Fatalf("ImportManifests() error = %v", err)
+	}
+	if len(result.Installed) != 1 {
+		t.Errorf("ImportManifests() installed %v", result.Installed)
+	}
+	info, err := os.Stat(filepath.Join(dest, "app.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("installed manifest mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func Test_LoadChecksumsInvalid(t *testing.T) {
+	root := writeBundle(t, map[string]string{ChecksumsFile: "not-a-sum file"})
+	if _, err := LoadChecksums(root); err == nil {
+		t.Errorf("LoadChecksums() expected an error")
+	}
+}
+
+func Test_HasLayoutFlatDirectory(t *testing.T) {
+	root := writeBundle(t, map[string]string{"app.tar": "image"})
+	if HasLayout(root) {
+		t.Errorf("HasLayout() = true for a flat image directory")
+	}
+}
@@ -0,0 +1,212 @@
+package content
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ImportStateFile records the images already imported, so unchanged archives are not copied again, and not hashed
+// again unless the bundle has a ChecksumsFile.
+// k3s only loads files with an image archive suffix from its images directory, so the file can live next to them.
+const ImportStateFile = ".provider-k3s-images.json"
+
+// imageSuffixes are the archive formats k3s imports from its images directory.
+var imageSuffixes = []string{".tar", ".tar.gz", ".tgz", ".tar.zst", ".tzst", ".tar.lz4", ".tar.bz2", ".tbz"}
+
+// ImageSummary describes the outcome of an image import.
+type ImageSummary struct {
+	Imported   []ImportedImage `json:"imported"`
+	Unchanged  []ImportedImage `json:"unchanged"`
+	Duplicates []ImportedImage `json:"duplicates,omitempty"`
+	Failed     []FailedImage   `json:"failed,omitempty"`
+}
+
+type ImportedImage struct {
+	Source string `json:"source"`
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+}
+
+type FailedImage struct {
+	Source string `json:"source"`
+	Error  string `json:"error"`
+}
+
+type importState struct {
+	// Sources caches the digest of source archives by path, size and modification time.
+	Sources map[string]sourceState `json:"sources"`
+	// Images maps digests to the file they were imported as.
+	Images map[string]string `json:"images"`
+}
+
+type sourceState struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	SHA256  string    `json:"sha256"`
+}
+
+// ImportImages copies the image archives of the bundle into the k3s images directory dest. Archives whose digest was
+// imported before and is still present in dest are skipped, as are archives with the same digest as another one.
+func (i *Importer) ImportImages(dest string) (ImageSummary, error) {
+	var summary ImageSummary
+
+	sources, err := i.imageSources()
+	if err != nil {
+		return summary, err
+	}
+	if err := os.MkdirAll(dest, 0700); err != nil {
+		return summary, err
+	}
+
+	state := loadImportState(dest)
+	seen := make(map[string]string)
+	taken := make(map[string]string)
+	for digest, file := range state.Images {
+		taken[file] = digest
+	}
+
+	var errs []error
+	for _, rel := range sources {
+		src := filepath.Join(i.Root, rel)
+		// A modified archive may keep its size and modification time, so listed checksums are checked against a
+		// fresh digest.
+		digest, err := state.digest(src, i.Checksums != nil)
+		if err == nil {
+			err = i.Checksums.VerifyDigest(rel, digest)
+		}
+		if err != nil {
+			summary.Failed = append(summary.Failed, FailedImage{Source: rel, Error: err.Error()})
+			errs = append(errs, err)
+			continue
+		}
+
+		if file, ok := seen[digest]; ok {
+			summary.Duplicates = append(summary.Duplicates, ImportedImage{Source: rel, File: file, SHA256: digest})
+			continue
+		}
+
+		if file, ok := state.Images[digest]; ok && fileExists(filepath.Join(dest, file)) {
+			seen[digest] = file
+			summary.Unchanged = append(summary.Unchanged, ImportedImage{Source: rel, File: file, SHA256: digest})
+			continue
+		}
+
+		file := filepath.Base(rel)
+		if owner, ok := taken[file]; ok && owner != digest {
+			file = fmt.Sprintf("%s-%s", digest[:12], file)
+		}
+		if err := CopyFile(src, filepath.Join(dest, file), 0600); err != nil {
+			summary.Failed = append(summary.Failed, FailedImage{Source: rel, Error: err.Error()})
+			errs = append(errs, err)
+			continue
+		}
+
+		seen[digest] = file
+		taken[file] = digest
+		state.Images[digest] = file
+		summary.Imported = append(summary.Imported, ImportedImage{Source: rel, File: file, SHA256: digest})
+	}
+
+	if err := state.save(dest); err != nil {
+		errs = append(errs, err)
+	}
+	return summary, errors.Join(errs...)
+}
+
+// imageSources lists the image archives of the bundle relative to its root. Flat bundles are searched as a whole.
+func (i *Importer) imageSources() ([]string, error) {
+	dir := i.Root
+	if HasLayout(i.Root) {
+		dir = filepath.Join(i.Root, ImagesDir)
+	}
+
+	var sources []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == dir {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || !hasSuffix(d.Name(), imageSuffixes) {
+			return nil
+		}
+		// Follow symlinked archives, as the previous import script did.
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(i.Root, path)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, rel)
+		return nil
+	})
+	sort.Strings(sources)
+	return sources, err
+}
+
+func loadImportState(dest string) *importState {
+	state := &importState{}
+	if data, err := os.ReadFile(filepath.Join(dest, ImportStateFile)); err == nil {
+		// A corrupt state only costs a re-import.
+		_ = json.Unmarshal(data, state)
+	}
+	if state.Sources == nil {
+		state.Sources = make(map[string]sourceState)
+	}
+	if state.Images == nil {
+		state.Images = make(map[string]string)
+	}
+	return state
+}
+
+func (s *importState) save(dest string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dest, ImportStateFile), data, 0600)
+}
+
+// digest returns the SHA256 of src. Unless rehash is set, the cached value is reused while the size and modification
+// time of src are unchanged.
+func (s *importState) digest(src string, rehash bool) (string, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return "", err
+	}
+	if cached, ok := s.Sources[src]; ok && !rehash && cached.Size == info.Size() && cached.ModTime.Equal(info.ModTime()) {
+		return cached.SHA256, nil
+	}
+
+	digest, err := FileSHA256(src)
+	if err != nil {
+		return "", err
+	}
+	s.Sources[src] = sourceState{Size: info.Size(), ModTime: info.ModTime(), SHA256: digest}
+	return digest, nil
+}
+
+// WriteSummary writes summary as JSON to path.
+func WriteSummary(path string, summary ImageSummary) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
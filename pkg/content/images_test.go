@@ -0,0 +1,120 @@
+package content
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ImportImages(t *testing.T) {
+	root := writeBundle(t, map[string]string{
+		"images/base.tar":         "base",
+		"images/apps/app.tar.zst": "app",
+		"images/z/copy.tar.gz":    "base",
+		"images/other/base.tar":   "other",
+		"images/notes.txt":        "ignored",
+		"charts/chart.tgz":        "chart",
+	})
+	dest := t.TempDir()
+
+	importer, err := NewImporter(root)
+	if err != nil {
+		t.Fatalf("NewImporter() error = %v", err)
+	}
+	summary, err := importer.ImportImages(dest)
+	if err != nil {
+		t.Fatalf("ImportImages() error = %v", err)
+	}
+	if len(summary.Imported) != 3 || len(summary.Duplicates) != 1 || len(summary.Unchanged) != 0 {
+		t.Fatalf("ImportImages() summary = %+v", summary)
+	}
+	for _, file := range []string{"base.tar", "app.tar.zst", fmt.Sprintf("%s-base.tar", sum("other")[:12])} {
+		if _, err := os.Stat(filepath.Join(dest, file)); err != nil {
+			t.Errorf("%s was not imported: %v", file, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dest, "chart.tgz")); !os.IsNotExist(err) {
+		t.Errorf("chart of a layout bundle was imported as an image")
+	}
+
+	summary, err = importer.ImportImages(dest)
+	if err != nil {
+		t.Fatalf("ImportImages() error = %v", err)
+	}
+	if len(summary.Imported) != 0 || len(summary.Unchanged) != 3 {
+		t.Errorf("second ImportImages() summary = %+v", summary)
+	}
+
+	if err := os.Remove(filepath.Join(dest, "app.tar.zst")); err != nil {
+		t.Fatal(err)
+	}
+	summary, err = importer.ImportImages(dest)
+	if err != nil {
+		t.Fatalf("ImportImages() error = %v", err)
+	}
+	if len(summary.Imported) != 1 || summary.Imported[0].File != "app.tar.zst" {
+		t.Errorf("ImportImages() did not re-import a removed image: %+v", summary)
+	}
+}
+
+func Test_ImportImagesVerifiesChecksums(t *testing.T) {
+	root := writeBundle(t, map[string]string{
+		"good.tar":    "good",
+		"bad.tar":     "bad",
+		ChecksumsFile: fmt.Sprintf("%s  good.tar\n%s  bad.tar\n", sum("good"), sum("tampered")),
+	})
+	dest := t.TempDir()
+
+	importer, err := NewImporter(root)
+	if err != nil {
+		t.Fatalf("NewImporter() error = %v", err)
+	}
+	summary, err := importer.ImportImages(dest)
+	if err == nil {
+		t.Errorf("ImportImages() expected a checksum error")
+	}
+	if len(summary.Imported) != 1 || summary.Imported[0].Source != "good.tar" {
+		t.Errorf("ImportImages() imported %+v", summary.Imported)
+	}
+	if len(summary.Failed) != 1 || summary.Failed[0].Source != "bad.tar" {
+		t.Errorf("ImportImages() failed %+v", summary.Failed)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "bad.tar")); !os.IsNotExist(err) {
+		t.Errorf("tampered image was imported")
+	}
+}
+
+func Test_ImportImagesRehashesWithChecksums(t *testing.T) {
+	root := writeBundle(t, map[string]string{
+		"app.tar":     "app1",
+		ChecksumsFile: fmt.Sprintf("%s  app.tar\n", sum("app1")),
+	})
+	dest := t.TempDir()
+
+	importer, err := NewImporter(root)
+	if err != nil {
+		t.Fatalf("NewImporter() error = %v", err)
+	}
+	if _, err := importer.ImportImages(dest); err != nil {
+		t.Fatalf("ImportImages() error = %v", err)
+	}
+
+	// Same size and modification time, different content.
+	src := filepath.Join(root, "app.tar")
+	info, err := os.Stat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("app2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(src, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := importer.ImportImages(dest)
+	if err == nil || len(summary.Failed) != 1 {
+		t.Errorf("ImportImages() of a modified archive = %+v, %v, want a checksum error", summary, err)
+	}
+}
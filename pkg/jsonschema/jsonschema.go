@@ -0,0 +1,177 @@
+package jsonschema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+const (
+	draft  = "https://json-schema.org/draft/2020-12/schema"
+	baseID = "https://github.com/kairos-io/provider-k3s/schema"
+
+	durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+)
+
+// Variant selects which config struct the schema is generated from.
+type Variant string
+
+const (
+	// VariantServer describes cluster.config on init and controlplane nodes.
+	VariantServer Variant = "server"
+	// VariantAgent describes cluster.config on worker nodes.
+	VariantAgent Variant = "agent"
+	// VariantAll accepts the keys of both roles, for configs shared between server and worker nodes.
+	VariantAll Variant = "all"
+)
+
+// Schema is the subset of JSON Schema the generator emits.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ParseVariant maps a variant or a node role to its Variant.
+func ParseVariant(s string) (Variant, error) {
+	switch s {
+	case string(VariantServer), clusterplugin.RoleInit, clusterplugin.RoleControlPlane:
+		return VariantServer, nil
+	case string(VariantAgent), clusterplugin.RoleWorker:
+		return VariantAgent, nil
+	case string(VariantAll), "":
+		return VariantAll, nil
+	}
+	return "", fmt.Errorf("unknown schema variant %q, expected one of server, agent, all or a node role", s)
+}
+
+// Generate returns the JSON Schema of the cluster.config section for the variant.
+func Generate(variant Variant) (*Schema, error) {
+	var fields []api.Field
+	var title string
+
+	switch variant {
+	case VariantServer:
+		fields, title = api.ServerFields(), "k3s server configuration"
+	case VariantAgent:
+		fields, title = api.AgentFields(), "k3s agent configuration"
+	case VariantAll:
+		fields, title = allFields(), "k3s configuration"
+	default:
+		return nil, fmt.Errorf("unknown schema variant %q", variant)
+	}
+
+	s := &Schema{
+		Schema:               draft,
+		ID:                   fmt.Sprintf("%s/%s.json", baseID, variant),
+		Title:                title,
+		Description:          "Keys of the cluster.config section of a Kairos cloud-config, as passed to k3s by provider-k3s",
+		Type:                 "object",
+		Properties:           make(map[string]*Schema, len(fields)),
+		AdditionalProperties: false,
+	}
+	for _, f := range fields {
+		property := forFlag(f.Type)
+		property.Description = f.Description
+		s.Properties[f.Key] = property
+	}
+	for _, f := range api.ProviderConfigFields() {
+		property := forType(f.Type)
+		property.Description = f.Description
+		s.Properties[f.Key] = property
+	}
+	return s, nil
+}
+
+// allFields merges the server and agent keys, noting in the description which role a key belongs to.
+func allFields() []api.Field {
+	server := api.ServerFields()
+	agent := api.AgentFields()
+
+	inServer := make(map[string]bool, len(server))
+	for _, f := range server {
+		inServer[f.Key] = true
+	}
+	inAgent := make(map[string]bool, len(agent))
+	for _, f := range agent {
+		inAgent[f.Key] = true
+	}
+
+	var out []api.Field
+	for _, f := range server {
+		if !inAgent[f.Key] {
+			f.Description = fmt.Sprintf("%s (server only)", f.Description)
+		}
+		out = append(out, f)
+	}
+	for _, f := range agent {
+		if !inServer[f.Key] {
+			f.Description = fmt.Sprintf("%s (agent only)", f.Description)
+			out = append(out, f)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func forType(t reflect.Type) *Schema {
+	if t == durationType {
+		return &Schema{Type: "string", Pattern: durationPattern}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return forType(t.Elem())
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+		for i := 0; i < t.NumField(); i++ {
+			key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if key == "" || key == "-" {
+				continue
+			}
+			s.Properties[key] = forType(t.Field(i).Type)
+		}
+		return s
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: forType(t.Elem())}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: forType(t.Elem())}
+	case reflect.Interface:
+		// Free-form values, such as chart values, accept any JSON value.
+		return &Schema{}
+	}
+	return &Schema{Type: "string"}
+}
+
+// forFlag describes a k3s flag. Unlike provider sections, list flags may also be given as a comma separated string,
+// see decodeOptions.
+func forFlag(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Slice {
+		return &Schema{
+			OneOf: []*Schema{
+				forType(t),
+				{Type: "string"},
+			},
+		}
+	}
+	return forType(t)
+}
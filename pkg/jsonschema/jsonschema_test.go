@@ -0,0 +1,207 @@
+package jsonschema
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+func Test_Generate(t *testing.T) {
+	tests := []struct {
+		name     string
+		variant  Variant
+		present  []string
+		absent   []string
+		expected map[string]string
+	}{
+		{
+			name:     "Server",
+			variant:  VariantServer,
+			present:  []string{"cluster-init", "kube-apiserver-arg", "node-label", "registries"},
+			absent:   []string{"disable-apiserver-lb"},
+			expected: map[string]string{"https-listen-port": "integer", "cluster-init": "boolean", "etcd-s3-timeout": "string"},
+		},
+		{
+			name:    "Agent",
+			variant: VariantAgent,
+			present: []string{"disable-apiserver-lb", "node-label"},
+			absent:  []string{"cluster-init", "kube-apiserver-arg"},
+		},
+		{
+			name:    "All",
+			variant: VariantAll,
+			present: []string{"cluster-init", "disable-apiserver-lb", "node-label"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Generate(tt.variant)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if s.AdditionalProperties != false {
+				t.Errorf("Generate() allows additional properties")
+			}
+			for _, key := range tt.present {
+				if _, ok := s.Properties[key]; !ok {
+					t.Errorf("Generate() is missing %q", key)
+				}
+			}
+			for _, key := range tt.absent {
+				if _, ok := s.Properties[key]; ok {
+					t.Errorf("Generate() unexpectedly has %q", key)
+				}
+			}
+			for key, typ := range tt.expected {
+				if got := s.Properties[key].Type; got != typ {
+					t.Errorf("Generate() %q type = %q, want %q", key, got, typ)
+				}
+			}
+		})
+	}
+}
+
+func Test_everyFieldHasADescription(t *testing.T) {
+	for _, f := range append(api.ServerFields(), api.AgentFields()...) {
+		if f.Description == "" {
+			t.Errorf("key %q has no entry in api.Descriptions", f.Key)
+		}
+	}
+}
+
+func Test_ParseVariant(t *testing.T) {
+	for in, want := range map[string]Variant{
+		"init":         VariantServer,
+		"controlplane": VariantServer,
+		"worker":       VariantAgent,
+		"all":          VariantAll,
+	} {
+		if got, err := ParseVariant(in); err != nil || got != want {
+			t.Errorf("ParseVariant(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseVariant("bogus"); err == nil {
+		t.Errorf("ParseVariant() expected an error")
+	}
+}
+
+func Test_readmeExamplesMatchSchema(t *testing.T) {
+	readme, err := os.ReadFile("../../README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Generate(VariantAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	examples := 0
+	for _, block := range regexp.MustCompile("(?s)```yaml\n(.*?)```").FindAllStringSubmatch(string(readme), -1) {
+		var doc struct {
+			Cluster struct {
+				Config string `yaml:"config"`
+			} `yaml:"cluster"`
+		}
+		if err := yaml.Unmarshal([]byte(block[1]), &doc); err != nil || doc.Cluster.Config == "" {
+			continue
+		}
+		var config map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc.Cluster.Config), &config); err != nil {
+			t.Errorf("README example is not valid YAML: %v\n%s", err, doc.Cluster.Config)
+			continue
+		}
+		if err := conforms(s, config, "config"); err != nil {
+			t.Errorf("README example does not match the schema: %v\n%s", err, doc.Cluster.Config)
+		}
+		examples++
+	}
+	if examples == 0 {
+		t.Fatalf("no README examples found")
+	}
+
+	if err := conforms(s, map[string]interface{}{"charts": []interface{}{map[string]interface{}{"name": "x", "values": map[string]interface{}{"replicas": "two"}}}}, "config"); err != nil {
+		t.Errorf("chart values are constrained: %v", err)
+	}
+	if err := conforms(s, map[string]interface{}{"charts": []interface{}{map[string]interface{}{"name": "x", "chartz": "y"}}}, "config"); err == nil {
+		t.Errorf("conforms() accepted an unknown chart key")
+	}
+}
+
+// conforms checks v, as decoded from YAML, against the subset of JSON Schema the generator emits.
+func conforms(s *Schema, v interface{}, path string) error {
+	if len(s.OneOf) > 0 {
+		matched := 0
+		for _, one := range s.OneOf {
+			if conforms(one, v, path) == nil {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return fmt.Errorf("%s matches %d of the oneOf schemas", path, matched)
+		}
+		return nil
+	}
+
+	switch s.Type {
+	case "":
+		return nil
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s is %T, want a string", path, v)
+		}
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(str) {
+			return fmt.Errorf("%s %q does not match %s", path, str, s.Pattern)
+		}
+	case "integer":
+		if _, ok := v.(int); !ok {
+			return fmt.Errorf("%s is %T, want an integer", path, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s is %T, want a boolean", path, v)
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s is %T, want an array", path, v)
+		}
+		for i, item := range items {
+			if err := conforms(s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s is %T, want an object", path, v)
+		}
+		for key, value := range object {
+			property, ok := s.Properties[key]
+			if !ok {
+				switch additional := s.AdditionalProperties.(type) {
+				case *Schema:
+					property = additional
+				case bool:
+					if !additional {
+						return fmt.Errorf("%s has unknown key %q", path, key)
+					}
+					continue
+				default:
+					continue
+				}
+			}
+			if err := conforms(property, value, path+"."+key); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%s has unsupported type %q", path, s.Type)
+	}
+	return nil
+}
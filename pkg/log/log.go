@@ -9,10 +9,14 @@ import (
 )
 
 func InitLogger(path string) {
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
 	if err != nil {
 		panic(err)
 	}
+	// Files created by older releases were world readable.
+	if err := f.Chmod(0600); err != nil {
+		panic(err)
+	}
 
 	logfile := &lumberjack.Logger{
 		Filename:   f.Name(),
@@ -33,7 +37,18 @@ type K3sLogger struct {
 	Formatter logrus.Formatter
 }
 
+// Format redacts secrets from the message and string fields before handing the entry to the wrapped formatter.
 func (l K3sLogger) Format(entry *logrus.Entry) ([]byte, error) {
-	entry.Data["version"] = l.Version
+	data := make(logrus.Fields, len(entry.Data)+1)
+	for k, v := range entry.Data {
+		if s, ok := v.(string); ok {
+			v = Redact(s)
+		}
+		data[k] = v
+	}
+	data["version"] = l.Version
+
+	entry.Data = data
+	entry.Message = Redact(entry.Message)
 	return l.Formatter.Format(entry)
 }
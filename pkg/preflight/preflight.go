@@ -0,0 +1,281 @@
+package preflight
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+// Status is the outcome of a single check.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	// StatusWarn marks problems k3s may recover from, such as modules it loads itself.
+	StatusWarn Status = "warn"
+	// StatusFail marks problems that keep k3s from running. Only these block startup.
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+var (
+	// RequiredCgroupControllers are the cgroup controllers the kubelet cannot run without.
+	RequiredCgroupControllers = []string{"cpu", "memory", "pids"}
+	// RequiredModules are the kernel modules k3s networking and containerd depend on.
+	RequiredModules = []string{"br_netfilter", "overlay"}
+)
+
+const (
+	// minFreeBytes is the free space below which the data dir is considered full.
+	minFreeBytes = 1 << 30
+	// warnFreeRatio matches the default kubelet imagefs eviction threshold.
+	warnFreeRatio = 0.15
+)
+
+// Result is the outcome of one check.
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+}
+
+// Report is written to the preflight report file.
+type Report struct {
+	Role    string    `json:"role"`
+	Time    time.Time `json:"time"`
+	Passed  bool      `json:"passed"`
+	Results []Result  `json:"results"`
+}
+
+// Options selects what Run checks.
+type Options struct {
+	Role    string
+	DataDir string
+	Ports   []int
+	Skip    []string
+
+	// Root prefixes /sys and /proc, for tests.
+	Root string
+	// ClockFloor is a time the clock must be past, see ClockFloor.
+	ClockFloor time.Time
+	Now        func() time.Time
+}
+
+// Run runs every check not skipped in opts. The report passes unless a check fails.
+func Run(opts Options) Report {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	report := Report{Role: opts.Role, Time: opts.Now().UTC(), Passed: true}
+	for _, name := range api.PreflightChecks {
+		var result Result
+		if slices.Contains(opts.Skip, name) {
+			result = Result{Status: StatusSkip, Message: "skipped by configuration"}
+		} else {
+			result = checks[name](opts)
+		}
+		result.Name = name
+		if result.Status == StatusFail {
+			report.Passed = false
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+var checks = map[string]func(Options) Result{
+	api.PreflightCgroups: checkCgroups,
+	api.PreflightModules: checkModules,
+	api.PreflightPorts:   checkPorts,
+	api.PreflightDisk:    checkDisk,
+	api.PreflightClock:   checkClock,
+}
+
+// checkCgroups reads the controllers of the unified hierarchy, falling back to /proc/cgroups on cgroup v1 hosts.
+func checkCgroups(opts Options) Result {
+	enabled := make(map[string]bool)
+	version := "v2"
+
+	if data, err := os.ReadFile(filepath.Join(opts.Root, "/sys/fs/cgroup/cgroup.controllers")); err == nil {
+		for _, c := range strings.Fields(string(data)) {
+			enabled[c] = true
+		}
+	} else {
+		version = "v1"
+		data, err := os.ReadFile(filepath.Join(opts.Root, "/proc/cgroups"))
+		if err != nil {
+			return Result{Status: StatusFail, Message: fmt.Sprintf("cannot read cgroup controllers: %s", err)}
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 4 && !strings.HasPrefix(fields[0], "#") && fields[3] == "1" {
+				enabled[fields[0]] = true
+			}
+		}
+	}
+
+	var missing []string
+	for _, c := range RequiredCgroupControllers {
+		if !enabled[c] {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		return Result{Status: StatusFail, Message: fmt.Sprintf("cgroup %s controllers missing: %s", version, strings.Join(missing, ", "))}
+	}
+	return Result{Status: StatusPass, Message: fmt.Sprintf("cgroup %s controllers %s enabled", version, strings.Join(RequiredCgroupControllers, ", "))}
+}
+
+// checkModules only warns, since k3s loads the modules itself when they are available.
+func checkModules(opts Options) Result {
+	var missing []string
+	for _, m := range RequiredModules {
+		if _, err := os.Stat(filepath.Join(opts.Root, "/sys/module", m)); err != nil {
+			missing = append(missing, m)
+		}
+	}
+	if len(missing) > 0 {
+		return Result{Status: StatusWarn, Message: fmt.Sprintf("kernel modules not loaded: %s", strings.Join(missing, ", "))}
+	}
+	return Result{Status: StatusPass, Message: fmt.Sprintf("kernel modules %s loaded", strings.Join(RequiredModules, ", "))}
+}
+
+// checkPorts binds each port to find the ones another process holds. A running k3s holds its own ports, so the check
+// is skipped when one is found.
+func checkPorts(opts Options) Result {
+	if len(opts.Ports) == 0 {
+		return Result{Status: StatusSkip, Message: "no ports to check"}
+	}
+	if k3sRunning(opts.Root) {
+		return Result{Status: StatusSkip, Message: "k3s is already running"}
+	}
+
+	var taken []string
+	for _, port := range opts.Ports {
+		l, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(port)))
+		if err != nil {
+			taken = append(taken, strconv.Itoa(port))
+			continue
+		}
+		l.Close()
+	}
+	if len(taken) > 0 {
+		return Result{Status: StatusFail, Message: fmt.Sprintf("ports already in use: %s", strings.Join(taken, ", "))}
+	}
+	return Result{Status: StatusPass, Message: fmt.Sprintf("ports %s available", joinInts(opts.Ports))}
+}
+
+func k3sRunning(root string) bool {
+	comms, _ := filepath.Glob(filepath.Join(root, "/proc/[0-9]*/comm"))
+	for _, path := range comms {
+		data, err := os.ReadFile(path)
+		if err == nil && strings.HasPrefix(strings.TrimSpace(string(data)), "k3s") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDisk checks the filesystem the data dir is or will be created on.
+func checkDisk(opts Options) Result {
+	dir := opts.DataDir
+	for {
+		if _, err := os.Stat(dir); err == nil || dir == filepath.Dir(dir) {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return Result{Status: StatusFail, Message: fmt.Sprintf("cannot stat filesystem of %s: %s", opts.DataDir, err)}
+	}
+	free := st.Bavail * uint64(st.Bsize)
+	total := st.Blocks * uint64(st.Bsize)
+
+	message := fmt.Sprintf("%s has %s free of %s", dir, formatBytes(free), formatBytes(total))
+	switch {
+	case free < minFreeBytes:
+		return Result{Status: StatusFail, Message: message}
+	case total > 0 && float64(free)/float64(total) < warnFreeRatio:
+		return Result{Status: StatusWarn, Message: message}
+	}
+	return Result{Status: StatusPass, Message: message}
+}
+
+// checkClock fails when the clock is behind ClockFloor, which breaks certificate validation as soon as k3s issues
+// its certificates. Without a time source a clock that is ahead cannot be detected, nor one that is behind by less
+// than the age of the floor.
+func checkClock(opts Options) Result {
+	now := opts.Now()
+	if opts.ClockFloor.IsZero() {
+		return Result{Status: StatusSkip, Message: "no reference time"}
+	}
+	if now.Before(opts.ClockFloor) {
+		return Result{Status: StatusFail, Message: fmt.Sprintf("clock %s is behind %s", now.UTC().Format(time.RFC3339), opts.ClockFloor.UTC().Format(time.RFC3339))}
+	}
+	return Result{Status: StatusPass, Message: fmt.Sprintf("clock %s", now.UTC().Format(time.RFC3339))}
+}
+
+// clockStatePatterns are files k3s writes under its data dir, relative to it. Their modification times were taken
+// from the clock of a previous boot, which issued certificates valid from then on.
+var clockStatePatterns = []string{"server/tls/*.crt", "server/cred/*", "agent/*.crt", "agent/*.kubeconfig"}
+
+// ClockFloor returns the latest of the build time of the binary and the modification times of the certificates and
+// credentials k3s wrote under dataDir. On a node that has run k3s before, the state is usually much more recent than
+// the build.
+func ClockFloor(build time.Time, dataDir string) time.Time {
+	floor := build
+	for _, pattern := range clockStatePatterns {
+		paths, _ := filepath.Glob(filepath.Join(dataDir, pattern))
+		for _, path := range paths {
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() && info.ModTime().After(floor) {
+				floor = info.ModTime()
+			}
+		}
+	}
+	return floor
+}
+
+// WriteReport writes the report as JSON, creating the parent directory.
+func WriteReport(path string, report Report) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func joinInts(values []int) string {
+	s := make([]string, 0, len(values))
+	for _, v := range values {
+		s = append(s, strconv.Itoa(v))
+	}
+	return strings.Join(s, ", ")
+}
+
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,190 @@
+package preflight
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_checkCgroups(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  Status
+	}{
+		{
+			name:  "v2",
+			files: map[string]string{"sys/fs/cgroup/cgroup.controllers": "cpuset cpu io memory hugetlb pids rdma\n"},
+			want:  StatusPass,
+		},
+		{
+			name:  "v2 without memory",
+			files: map[string]string{"sys/fs/cgroup/cgroup.controllers": "cpuset cpu io pids\n"},
+			want:  StatusFail,
+		},
+		{
+			name: "v1",
+			files: map[string]string{"proc/cgroups": `#subsys_name	hierarchy	num_cgroups	enabled
+cpu	2	1	1
+memory	3	1	1
+pids	4	1	1
+`},
+			want: StatusPass,
+		},
+		{
+			name: "v1 with memory disabled",
+			files: map[string]string{"proc/cgroups": `#subsys_name	hierarchy	num_cgroups	enabled
+cpu	2	1	1
+memory	0	1	0
+pids	4	1	1
+`},
+			want: StatusFail,
+		},
+		{
+			name: "Unreadable",
+			want: StatusFail,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+			if got := checkCgroups(Options{Root: root}); got.Status != tt.want {
+				t.Errorf("checkCgroups() = %+v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkModules(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"sys/module/overlay/refcnt": "0\n"})
+	if got := checkModules(Options{Root: root}); got.Status != StatusWarn {
+		t.Errorf("checkModules() = %+v, want %s", got, StatusWarn)
+	}
+
+	writeFiles(t, root, map[string]string{"sys/module/br_netfilter/refcnt": "0\n"})
+	if got := checkModules(Options{Root: root}); got.Status != StatusPass {
+		t.Errorf("checkModules() = %+v, want %s", got, StatusPass)
+	}
+}
+
+func Test_checkPorts(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	taken := l.Addr().(*net.TCPAddr).Port
+
+	root := t.TempDir()
+	if got := checkPorts(Options{Root: root, Ports: []int{taken}}); got.Status != StatusFail {
+		t.Errorf("checkPorts() = %+v, want %s", got, StatusFail)
+	}
+
+	writeFiles(t, root, map[string]string{"proc/42/comm": "k3s-server\n"})
+	if got := checkPorts(Options{Root: root, Ports: []int{taken}}); got.Status != StatusSkip {
+		t.Errorf("checkPorts() with k3s running = %+v, want %s", got, StatusSkip)
+	}
+}
+
+func Test_checkDisk(t *testing.T) {
+	dir := t.TempDir()
+	got := checkDisk(Options{DataDir: filepath.Join(dir, "missing", "k3s")})
+	if !strings.HasPrefix(got.Message, dir+" has ") {
+		t.Errorf("checkDisk() did not check the nearest existing directory: %+v", got)
+	}
+}
+
+func Test_checkClock(t *testing.T) {
+	floor := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		now  time.Time
+		want Status
+	}{
+		{now: time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), want: StatusFail},
+		{now: floor.Add(time.Hour), want: StatusPass},
+	}
+	for _, tt := range tests {
+		got := checkClock(Options{ClockFloor: floor, Now: func() time.Time { return tt.now }})
+		if got.Status != tt.want {
+			t.Errorf("checkClock(%s) = %+v, want %s", tt.now, got, tt.want)
+		}
+	}
+	if got := checkClock(Options{Now: time.Now}); got.Status != StatusSkip {
+		t.Errorf("checkClock() without floor = %+v, want %s", got, StatusSkip)
+	}
+}
+
+func Test_ClockFloor(t *testing.T) {
+	dataDir := t.TempDir()
+	build := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := ClockFloor(build, dataDir); !got.Equal(build) {
+		t.Errorf("ClockFloor() on a new node = %s, want the build time %s", got, build)
+	}
+
+	writeFiles(t, dataDir, map[string]string{"server/tls/server-ca.crt": "ca", "server/db/state.db": "db"})
+	issued := build.Add(90 * 24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dataDir, "server/tls/server-ca.crt"), issued, issued); err != nil {
+		t.Fatal(err)
+	}
+	unrelated := issued.Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dataDir, "server/db/state.db"), unrelated, unrelated); err != nil {
+		t.Fatal(err)
+	}
+	if got := ClockFloor(build, dataDir); !got.Equal(issued) {
+		t.Errorf("ClockFloor() = %s, want the certificate time %s", got, issued)
+	}
+	if got := ClockFloor(time.Time{}, dataDir); !got.Equal(issued) {
+		t.Errorf("ClockFloor() without a build time = %s, want %s", got, issued)
+	}
+}
+
+func Test_Run(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"sys/fs/cgroup/cgroup.controllers": "cpu memory\n"})
+
+	report := Run(Options{
+		Role:    "worker",
+		Root:    root,
+		DataDir: root,
+		Skip:    []string{api.PreflightPorts, api.PreflightDisk},
+	})
+	if report.Passed {
+		t.Errorf("Run() passed with missing cgroup controllers: %+v", report)
+	}
+	if len(report.Results) != len(api.PreflightChecks) {
+		t.Fatalf("Run() returned %d results, want %d", len(report.Results), len(api.PreflightChecks))
+	}
+	for _, r := range report.Results {
+		if (r.Name == api.PreflightPorts || r.Name == api.PreflightDisk) && r.Status != StatusSkip {
+			t.Errorf("check %s = %s, want %s", r.Name, r.Status, StatusSkip)
+		}
+	}
+
+	path := filepath.Join(root, "run", "preflight.json")
+	if err := WriteReport(path, report); err != nil {
+		t.Fatalf("WriteReport() error = %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("report not written: %v", err)
+	}
+}
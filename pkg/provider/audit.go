@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	yip "github.com/mudler/yip/pkg/schema"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+// cisAudit is the audit configuration of the CIS profile when no audit section is given.
+var cisAudit = api.Audit{
+	Level:     api.AuditLevelMetadata,
+	MaxAge:    intPtr(30),
+	MaxBackup: intPtr(10),
+	MaxSize:   intPtr(100),
+}
+
+// getAudit returns the audit configuration of server nodes: the audit section, or the CIS defaults with that profile.
+func getAudit(cluster clusterplugin.Cluster, providerConfig api.ProviderConfig) *api.Audit {
+	if cluster.Role == clusterplugin.RoleWorker {
+		return nil
+	}
+	if providerConfig.Audit != nil {
+		return providerConfig.Audit
+	}
+	if providerConfig.Profile == api.ProfileCIS {
+		audit := cisAudit
+		return &audit
+	}
+	return nil
+}
+
+// getAuditKubeAPIServerArgs points the API server at the audit policy and log. Flags the cluster config already
+// passes in kube-apiserver-arg are left to it.
+func getAuditKubeAPIServerArgs(cluster clusterplugin.Cluster, audit *api.Audit, options map[string]interface{}) []string {
+	if audit == nil {
+		return nil
+	}
+
+	dataDir := getDataDir(cluster)
+	logPath := audit.LogPath
+	if logPath == "" {
+		logPath = filepath.Join(dataDir, constants.K3sAuditLogFile)
+	}
+	args := []string{
+		"audit-policy-file=" + filepath.Join(dataDir, constants.K3sAuditPolicyFile),
+		"audit-log-path=" + logPath,
+	}
+	for _, rotation := range []struct {
+		flag  string
+		value *int
+	}{
+		{"audit-log-maxage", audit.MaxAge},
+		{"audit-log-maxbackup", audit.MaxBackup},
+		{"audit-log-maxsize", audit.MaxSize},
+	} {
+		if rotation.value != nil {
+			args = append(args, rotation.flag+"="+strconv.Itoa(*rotation.value))
+		}
+	}
+
+	return withoutUserArgs("kube-apiserver-arg", args, options)
+}
+
+// getAuditFiles renders the audit policy.
+func getAuditFiles(cluster clusterplugin.Cluster, audit *api.Audit) ([]yip.File, error) {
+	if audit == nil {
+		return nil, nil
+	}
+	if err := api.ValidateAudit(audit); err != nil {
+		return nil, err
+	}
+
+	policy := audit.Policy
+	if policy == "" {
+		level := audit.Level
+		if level == "" {
+			level = api.AuditLevelMetadata
+		}
+		policy = fmt.Sprintf(`apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+  - level: %s
+`, level)
+	}
+
+	return []yip.File{
+		{
+			Path:        filepath.Join(getDataDir(cluster), constants.K3sAuditPolicyFile),
+			Permissions: 0600,
+			Content:     policy,
+		},
+	}, nil
+}
+
+func intPtr(i int) *int {
+	return &i
+}
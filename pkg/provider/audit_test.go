@@ -0,0 +1,125 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+func Test_audit(t *testing.T) {
+	customPolicy := "apiVersion: audit.k8s.io/v1\nkind: Policy\nrules:\n  - level: None\n"
+
+	tests := []struct {
+		name           string
+		role           clusterplugin.Role
+		providerConfig api.ProviderConfig
+		options        map[string]interface{}
+		wantArgs       []string
+		wantPolicy     string
+	}{
+		{
+			name: "Disabled",
+			role: clusterplugin.RoleInit,
+		},
+		{
+			name:           "Default level",
+			role:           clusterplugin.RoleInit,
+			providerConfig: api.ProviderConfig{Audit: &api.Audit{}},
+			wantArgs: []string{
+				"audit-policy-file=/var/lib/rancher/k3s/server/audit.yaml",
+				"audit-log-path=/var/lib/rancher/k3s/server/logs/audit.log",
+			},
+			wantPolicy: "  - level: Metadata\n",
+		},
+		{
+			name: "Level and rotation",
+			role: clusterplugin.RoleControlPlane,
+			providerConfig: api.ProviderConfig{Audit: &api.Audit{
+				Level:     api.AuditLevelRequestResponse,
+				LogPath:   "/var/log/k3s-audit.log",
+				MaxAge:    intPtr(7),
+				MaxBackup: intPtr(0),
+			}},
+			wantArgs: []string{
+				"audit-policy-file=/var/lib/rancher/k3s/server/audit.yaml",
+				"audit-log-path=/var/log/k3s-audit.log",
+				"audit-log-maxage=7",
+				"audit-log-maxbackup=0",
+			},
+			wantPolicy: "  - level: RequestResponse\n",
+		},
+		{
+			name:           "Custom policy",
+			role:           clusterplugin.RoleInit,
+			providerConfig: api.ProviderConfig{Audit: &api.Audit{Policy: customPolicy}},
+			wantArgs: []string{
+				"audit-policy-file=/var/lib/rancher/k3s/server/audit.yaml",
+				"audit-log-path=/var/lib/rancher/k3s/server/logs/audit.log",
+			},
+			wantPolicy: customPolicy,
+		},
+		{
+			name:           "CIS profile",
+			role:           clusterplugin.RoleInit,
+			providerConfig: api.ProviderConfig{Profile: api.ProfileCIS},
+			wantArgs: []string{
+				"audit-policy-file=/var/lib/rancher/k3s/server/audit.yaml",
+				"audit-log-path=/var/lib/rancher/k3s/server/logs/audit.log",
+				"audit-log-maxage=30",
+				"audit-log-maxbackup=10",
+				"audit-log-maxsize=100",
+			},
+			wantPolicy: "  - level: Metadata\n",
+		},
+		{
+			name:           "User args",
+			role:           clusterplugin.RoleInit,
+			providerConfig: api.ProviderConfig{Audit: &api.Audit{MaxSize: intPtr(50)}},
+			options: map[string]interface{}{
+				"kube-apiserver-arg": []interface{}{"audit-log-path=-", "audit-log-maxsize=500"},
+			},
+			wantArgs: []string{
+				"audit-policy-file=/var/lib/rancher/k3s/server/audit.yaml",
+			},
+			wantPolicy: "  - level: Metadata\n",
+		},
+		{
+			name:           "Worker",
+			role:           clusterplugin.RoleWorker,
+			providerConfig: api.ProviderConfig{Audit: &api.Audit{}, Profile: api.ProfileCIS},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := api.ValidateAudit(tt.providerConfig.Audit); err != nil {
+				t.Fatalf("ValidateAudit() error = %v", err)
+			}
+			cluster := clusterplugin.Cluster{Role: tt.role}
+			audit := getAudit(cluster, tt.providerConfig)
+
+			if args := getAuditKubeAPIServerArgs(cluster, audit, tt.options); strings.Join(args, ",") != strings.Join(tt.wantArgs, ",") {
+				t.Errorf("getAuditKubeAPIServerArgs() = %v, want %v", args, tt.wantArgs)
+			}
+
+			files, err := getAuditFiles(cluster, audit)
+			if err != nil {
+				t.Fatalf("getAuditFiles() error = %v", err)
+			}
+			if tt.wantPolicy == "" {
+				if len(files) != 0 {
+					t.Errorf("getAuditFiles() = %+v, want none", files)
+				}
+				return
+			}
+			if len(files) != 1 || files[0].Path != "/var/lib/rancher/k3s/server/audit.yaml" || files[0].Permissions != 0600 {
+				t.Fatalf("getAuditFiles() = %+v", files)
+			}
+			if !strings.Contains(files[0].Content, tt.wantPolicy) {
+				t.Errorf("audit policy does not contain %q:\n%s", tt.wantPolicy, files[0].Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	yip "github.com/mudler/yip/pkg/schema"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+// encryptionConfiguration is the apiserver.config.k8s.io EncryptionConfiguration.
+type encryptionConfiguration struct {
+	APIVersion string                `yaml:"apiVersion"`
+	Kind       string                `yaml:"kind"`
+	Resources  []encryptionResources `yaml:"resources"`
+}
+
+type encryptionResources struct {
+	Resources []string             `yaml:"resources"`
+	Providers []encryptionProvider `yaml:"providers"`
+}
+
+// encryptionProvider holds one of the providers. Identity comes last, so resources written before encryption was
+// turned on stay readable, or first in decrypt mode.
+type encryptionProvider struct {
+	AESCBC    *encryptionKeys `yaml:"aescbc,omitempty"`
+	Secretbox *encryptionKeys `yaml:"secretbox,omitempty"`
+	Identity  *struct{}       `yaml:"identity,omitempty"`
+}
+
+type encryptionKeys struct {
+	Keys []api.EncryptionKey `yaml:"keys"`
+}
+
+// getEncryption returns the encryption section on server nodes.
+func getEncryption(cluster clusterplugin.Cluster, providerConfig api.ProviderConfig) *api.Encryption {
+	if cluster.Role == clusterplugin.RoleWorker {
+		return nil
+	}
+	return providerConfig.Encryption
+}
+
+// getEncryptionKubeAPIServerArgs points the API server at the encryption config. The keys k3s manages with
+// secrets-encryption would be ignored, so enabling both is an error.
+func getEncryptionKubeAPIServerArgs(cluster clusterplugin.Cluster, encryption *api.Encryption, options map[string]interface{}) ([]string, error) {
+	if encryption == nil {
+		return nil, nil
+	}
+
+	cfg, err := decodeServerOptions(options, "secrets-encryption")
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets-encryption option: %w", err)
+	}
+	if cfg.SecretsEncryption {
+		return nil, fmt.Errorf("encryption: the encryption section replaces secrets-encryption, remove one of them")
+	}
+
+	args := []string{"encryption-provider-config=" + filepath.Join(getDataDir(cluster), constants.K3sEncryptionConfigFile)}
+	return withoutUserArgs("kube-apiserver-arg", args, options), nil
+}
+
+// getEncryptionFiles renders the EncryptionConfiguration on server nodes. Every key of the config already on the
+// node, and of the config k3s manages with secrets-encryption, must be kept or retired, since dropping a key makes the
+// resources it encrypted unreadable. That includes removing the section, which is only allowed once the config on the
+// node is in decrypt mode. With skipHostState the node is assumed to hold no keys yet.
+func getEncryptionFiles(cluster clusterplugin.Cluster, encryption *api.Encryption, skipHostState bool) ([]yip.File, error) {
+	if cluster.Role == clusterplugin.RoleWorker {
+		return nil, nil
+	}
+
+	dataDir := getDataDir(cluster)
+	path := filepath.Join(dataDir, constants.K3sEncryptionConfigFile)
+	managedPath := filepath.Join(dataDir, constants.K3sManagedEncryptionConfigFile)
+	var current, managed encryptionConfiguration
+	if !skipHostState {
+		var err error
+		if current, err = readEncryptionConfig(path); err != nil {
+			return nil, err
+		}
+		if managed, err = readEncryptionConfig(managedPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if encryption == nil {
+		if dropped := droppedEncryptionKeys(current, encryptionConfiguration{}, nil); len(dropped) > 0 && !decryptMode(current) {
+			return nil, fmt.Errorf("encryption: the section was removed but keys %s of %s are still in use, set decrypt and rewrite every resource before removing it", strings.Join(dropped, ", "), path)
+		}
+		return nil, nil
+	}
+	if err := api.ValidateEncryption(encryption); err != nil {
+		return nil, err
+	}
+
+	current.Resources = append(current.Resources, managed.Resources...)
+
+	config := encryptionConfig(encryption)
+	if dropped := droppedEncryptionKeys(current, config, encryption.RetiredKeys); len(dropped) > 0 {
+		return nil, fmt.Errorf("encryption: keys %s of %s or %s are still in use, keep them or list them in retiredKeys once every resource was rewritten", strings.Join(dropped, ", "), path, managedPath)
+	}
+
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal encryption config: %w", err)
+	}
+
+	return []yip.File{
+		{
+			Path:        path,
+			Permissions: 0600,
+			Content:     string(content),
+		},
+	}, nil
+}
+
+// encryptionConfig renders the section. In decrypt mode identity comes first, so new writes are not encrypted.
+func encryptionConfig(encryption *api.Encryption) encryptionConfiguration {
+	keys := &encryptionKeys{Keys: encryption.Keys}
+	provider := encryptionProvider{}
+	switch encryption.EncryptionProvider() {
+	case api.EncryptionAESCBC:
+		provider.AESCBC = keys
+	case api.EncryptionSecretbox:
+		provider.Secretbox = keys
+	}
+	identity := encryptionProvider{Identity: &struct{}{}}
+	providers := []encryptionProvider{provider, identity}
+	if encryption.Decrypt {
+		providers = []encryptionProvider{identity, provider}
+	}
+
+	resources := encryption.Resources
+	if len(resources) == 0 {
+		resources = []string{"secrets"}
+	}
+
+	return encryptionConfiguration{
+		APIVersion: "apiserver.config.k8s.io/v1",
+		Kind:       "EncryptionConfiguration",
+		Resources: []encryptionResources{
+			{
+				Resources: resources,
+				Providers: providers,
+			},
+		},
+	}
+}
+
+// readEncryptionConfig reads the config the API server currently uses, if any.
+func readEncryptionConfig(path string) (encryptionConfiguration, error) {
+	var config encryptionConfiguration
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to read encryption config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to un-marshal encryption config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// decryptMode reports whether every resource of the config is written unencrypted, so its keys are only kept to read
+// resources that were not rewritten yet.
+func decryptMode(config encryptionConfiguration) bool {
+	for _, resources := range config.Resources {
+		if len(resources.Providers) == 0 || resources.Providers[0].Identity == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// droppedEncryptionKeys lists the keys of current, as provider/name, that next no longer holds with the same secret
+// and that are not retired.
+func droppedEncryptionKeys(current, next encryptionConfiguration, retired []string) []string {
+	kept := make(map[string]bool)
+	for key := range encryptionConfigKeys(next) {
+		kept[key] = true
+	}
+
+	var dropped []string
+	for key, name := range encryptionConfigKeys(current) {
+		if !kept[key] && !slices.Contains(retired, name) {
+			dropped = append(dropped, name)
+		}
+	}
+	slices.Sort(dropped)
+	return slices.Compact(dropped)
+}
+
+// encryptionConfigKeys maps each key of the config, identified by provider, name and secret, to provider/name.
+func encryptionConfigKeys(config encryptionConfiguration) map[string]string {
+	keys := make(map[string]string)
+	for _, resources := range config.Resources {
+		for _, provider := range resources.Providers {
+			for name, providerKeys := range map[string]*encryptionKeys{
+				api.EncryptionAESCBC:    provider.AESCBC,
+				api.EncryptionSecretbox: provider.Secretbox,
+			} {
+				if providerKeys == nil {
+					continue
+				}
+				for _, key := range providerKeys.Keys {
+					keys[name+"/"+key.Name+"/"+key.Secret] = key.Name
+				}
+			}
+		}
+	}
+	return keys
+}
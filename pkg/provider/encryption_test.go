@@ -0,0 +1,247 @@
+package provider
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+const (
+	testKey1 = "c2VjcmV0LWtleS0xLXRoaXJ0eS10d28tYnl0ZXMhISE=" // 32 bytes
+	testKey2 = "c2VjcmV0LWtleS0yLXRoaXJ0eS10d28tYnl0ZXMhISE=" // 32 bytes
+)
+
+func Test_encryptionFiles(t *testing.T) {
+	tests := []struct {
+		name       string
+		current    string
+		decrypting bool
+		managed    string
+		skipHost   bool
+		encryption *api.Encryption
+		wantErr    string
+		want       []string
+		wantNone   bool
+	}{
+		{
+			name:       "Default provider",
+			encryption: &api.Encryption{Keys: []api.EncryptionKey{{Name: "key1", Secret: testKey1}}},
+			want:       []string{"kind: EncryptionConfiguration", "- secrets", "aescbc:", "name: key1", "secret: " + testKey1, "identity: {}"},
+		},
+		{
+			name: "Secretbox",
+			encryption: &api.Encryption{
+				Provider:  api.EncryptionSecretbox,
+				Keys:      []api.EncryptionKey{{Name: "key1", Secret: testKey1}},
+				Resources: []string{"secrets", "configmaps"},
+			},
+			want: []string{"secretbox:", "- configmaps"},
+		},
+		{
+			name:       "Rotation keeps the current key",
+			current:    "key1",
+			encryption: &api.Encryption{Keys: []api.EncryptionKey{{Name: "key2", Secret: testKey2}, {Name: "key1", Secret: testKey1}}},
+			want:       []string{"name: key2", "name: key1"},
+		},
+		{
+			name:       "Dropped key",
+			current:    "key1",
+			encryption: &api.Encryption{Keys: []api.EncryptionKey{{Name: "key2", Secret: testKey2}}},
+			wantErr:    "keys key1 of",
+		},
+		{
+			name:       "Changed secret",
+			current:    "key1",
+			encryption: &api.Encryption{Keys: []api.EncryptionKey{{Name: "key1", Secret: testKey2}}},
+			wantErr:    "keys key1 of",
+		},
+		{
+			name:       "Retired key",
+			current:    "key1",
+			encryption: &api.Encryption{Keys: []api.EncryptionKey{{Name: "key2", Secret: testKey2}}, RetiredKeys: []string{"key1"}},
+			want:       []string{"name: key2"},
+		},
+		{
+			name:     "No section",
+			wantNone: true,
+		},
+		{
+			name:    "Removed section",
+			current: "key1",
+			wantErr: "the section was removed but keys key1 of",
+		},
+		{
+			name:       "Decrypt mode",
+			current:    "key1",
+			encryption: &api.Encryption{Keys: []api.EncryptionKey{{Name: "key1", Secret: testKey1}}, Decrypt: true},
+			want:       []string{"identity: {}", "name: key1"},
+		},
+		{
+			name:       "Removed section after decrypt mode",
+			current:    "key1",
+			decrypting: true,
+			wantNone:   true,
+		},
+		{
+			name:       "Only retired keys",
+			current:    "key1",
+			encryption: &api.Encryption{RetiredKeys: []string{"key1"}},
+			wantErr:    "at least one key is required",
+		},
+		{
+			name:       "Switch from secrets-encryption",
+			managed:    "aescbckey",
+			encryption: &api.Encryption{Keys: []api.EncryptionKey{{Name: "key1", Secret: testKey1}}},
+			wantErr:    "keys aescbckey of",
+		},
+		{
+			name:    "Migrated from secrets-encryption",
+			managed: "aescbckey",
+			encryption: &api.Encryption{Keys: []api.EncryptionKey{
+				{Name: "key1", Secret: testKey1},
+				{Name: "aescbckey", Secret: testKey2},
+			}},
+			want: []string{"name: key1", "name: aescbckey"},
+		},
+		{
+			name:       "Host state skipped",
+			current:    "key1",
+			managed:    "aescbckey",
+			skipHost:   true,
+			encryption: &api.Encryption{Keys: []api.EncryptionKey{{Name: "key2", Secret: testKey2}}},
+			want:       []string{"name: key2"},
+		},
+		{
+			name:     "Removed section with host state skipped",
+			current:  "key1",
+			skipHost: true,
+			wantNone: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataDir := t.TempDir()
+			cluster := clusterplugin.Cluster{Role: clusterplugin.RoleInit, Options: "data-dir: " + dataDir}
+			path := filepath.Join(dataDir, "server/encryption.yaml")
+
+			if tt.current != "" {
+				current, err := getEncryptionFiles(cluster, &api.Encryption{Keys: []api.EncryptionKey{{Name: tt.current, Secret: testKey1}}, Decrypt: tt.decrypting}, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(current[0].Content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.managed != "" {
+				managed := filepath.Join(dataDir, "server/cred/encryption-config.json")
+				if err := os.MkdirAll(filepath.Dir(managed), 0700); err != nil {
+					t.Fatal(err)
+				}
+				content := `{"kind":"EncryptionConfiguration","apiVersion":"apiserver.config.k8s.io/v1","resources":[{"resources":["secrets"],` +
+					`"providers":[{"aescbc":{"keys":[{"name":"` + tt.managed + `","secret":"` + testKey2 + `"}]}},{"identity":{}}]}]}`
+				if err := os.WriteFile(managed, []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			files, err := getEncryptionFiles(cluster, tt.encryption, tt.skipHost)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("getEncryptionFiles() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getEncryptionFiles() error = %v", err)
+			}
+			if tt.wantNone {
+				if len(files) != 0 {
+					t.Errorf("getEncryptionFiles() = %+v, want none", files)
+				}
+				return
+			}
+			if len(files) != 1 || files[0].Path != path || files[0].Permissions != 0600 {
+				t.Fatalf("getEncryptionFiles() = %+v", files)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(files[0].Content, want) {
+					t.Errorf("encryption config does not contain %q:\n%s", want, files[0].Content)
+				}
+			}
+			var config encryptionConfiguration
+			if err := yaml.Unmarshal([]byte(files[0].Content), &config); err != nil {
+				t.Fatal(err)
+			}
+			providers := config.Resources[0].Providers
+			if first := providers[0]; tt.encryption.Decrypt && first.Identity == nil {
+				t.Errorf("identity is not the first provider in decrypt mode:\n%s", files[0].Content)
+			}
+			if last := providers[len(providers)-1]; !tt.encryption.Decrypt && last.Identity == nil {
+				t.Errorf("identity is not the last provider:\n%s", files[0].Content)
+			}
+		})
+	}
+}
+
+func Test_encryptionKubeAPIServerArgs(t *testing.T) {
+	cluster := clusterplugin.Cluster{Role: clusterplugin.RoleInit}
+	encryption := &api.Encryption{Keys: []api.EncryptionKey{{Name: "key1", Secret: testKey1}}}
+
+	args, err := getEncryptionKubeAPIServerArgs(cluster, encryption, nil)
+	if err != nil || strings.Join(args, ",") != "encryption-provider-config=/var/lib/rancher/k3s/server/encryption.yaml" {
+		t.Errorf("getEncryptionKubeAPIServerArgs() = %v, %v", args, err)
+	}
+	decrypt := &api.Encryption{Keys: encryption.Keys, Decrypt: true}
+	if args, err := getEncryptionKubeAPIServerArgs(cluster, decrypt, nil); err != nil || len(args) != 1 {
+		t.Errorf("getEncryptionKubeAPIServerArgs() in decrypt mode = %v, %v", args, err)
+	}
+	if _, err := getEncryptionKubeAPIServerArgs(cluster, encryption, map[string]interface{}{"secrets-encryption": true}); err == nil {
+		t.Errorf("getEncryptionKubeAPIServerArgs() expected an error with secrets-encryption")
+	}
+	if getEncryption(clusterplugin.Cluster{Role: clusterplugin.RoleWorker}, api.ProviderConfig{Encryption: encryption}) != nil {
+		t.Errorf("getEncryption() returned the section on a worker")
+	}
+
+	files, err := getProfileFiles(clusterplugin.Cluster{Role: clusterplugin.RoleInit}, nil, api.ProviderConfig{Profile: api.ProfileCIS, Encryption: encryption})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(files[0].Content, "secrets-encryption") {
+		t.Errorf("profile enables secrets-encryption along with the encryption section:\n%s", files[0].Content)
+	}
+}
+
+func Test_encryptionKeysNotLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+	t.Cleanup(func() { logrus.SetOutput(os.Stderr) })
+
+	dataDir := t.TempDir()
+	cluster := clusterplugin.Cluster{
+		ClusterToken: "token",
+		Role:         clusterplugin.RoleInit,
+		Options: "data-dir: " + dataDir + `
+encryption:
+  keys:
+    - name: key1
+      secret: ` + testKey1,
+	}
+	if _, err := BuildConfig(cluster, RenderOptions{}); err != nil {
+		t.Fatalf("BuildConfig() error = %v", err)
+	}
+	if strings.Contains(buf.String(), testKey1) {
+		t.Errorf("encryption key was logged:\n%s", buf.String())
+	}
+}
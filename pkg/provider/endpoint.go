@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+const defaultAPIPort = 6443
+
+var hostnamePattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*\.?$`)
+
+// controlPlaneEndpoint is ControlPlaneHost split into its parts. Host never carries brackets, a port or a scheme.
+type controlPlaneEndpoint struct {
+	Host string
+	// Port is 0 when ControlPlaneHost does not name one.
+	Port int
+}
+
+// parseControlPlaneHost accepts an IPv4 address, an IPv6 address with or without brackets, or a hostname, each with
+// an optional port and an optional https:// scheme.
+func parseControlPlaneHost(s string) (controlPlaneEndpoint, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return controlPlaneEndpoint{}, nil
+	}
+
+	host, port := s, ""
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return controlPlaneEndpoint{}, fmt.Errorf("invalid control plane host %q: %w", s, err)
+		}
+		if u.Scheme != "https" {
+			return controlPlaneEndpoint{}, fmt.Errorf("invalid control plane host %q: the k3s API is only served over https", s)
+		}
+		if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+			return controlPlaneEndpoint{}, fmt.Errorf("invalid control plane host %q: only a host and port are allowed", s)
+		}
+		host, port = u.Hostname(), u.Port()
+	} else if net.ParseIP(s) == nil && strings.Contains(s, ":") && !isBracketedIP(s) {
+		var err error
+		host, port, err = net.SplitHostPort(s)
+		if err != nil {
+			return controlPlaneEndpoint{}, fmt.Errorf("invalid control plane host %q: %w", s, err)
+		}
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	if net.ParseIP(host) == nil && !hostnamePattern.MatchString(host) {
+		return controlPlaneEndpoint{}, fmt.Errorf("invalid control plane host %q: %q is neither an IP address nor a hostname", s, host)
+	}
+
+	endpoint := controlPlaneEndpoint{Host: host}
+	if port != "" {
+		p, err := parsePort("port", port)
+		if err != nil {
+			return controlPlaneEndpoint{}, fmt.Errorf("invalid control plane host %q: %w", s, err)
+		}
+		endpoint.Port = p
+	}
+	return endpoint, nil
+}
+
+// getAPIPort returns the port servers serve the k3s API on, for join URLs whose ControlPlaneHost has no port. The
+// https-listen-port provider option wins over the one in the cluster options, as it does in the rendered config.
+func getAPIPort(cluster clusterplugin.Cluster, options map[string]interface{}) (int, error) {
+	if v, ok := cluster.ProviderOptions[constants.HTTPSListenPort]; ok {
+		return parsePort(constants.HTTPSListenPort, v)
+	}
+	switch v := options[constants.HTTPSListenPort].(type) {
+	case nil:
+		return defaultAPIPort, nil
+	case int:
+		return parsePort(constants.HTTPSListenPort, strconv.Itoa(v))
+	case string:
+		return parsePort(constants.HTTPSListenPort, v)
+	default:
+		return 0, fmt.Errorf("invalid %s %v", constants.HTTPSListenPort, v)
+	}
+}
+
+func parsePort(name, s string) (int, error) {
+	p, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || p < 1 || p > 65535 {
+		return 0, fmt.Errorf("invalid %s %q", name, s)
+	}
+	return p, nil
+}
+
+func isBracketedIP(s string) bool {
+	return strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") && net.ParseIP(s[1:len(s)-1]) != nil
+}
+
+// URL returns the https URL of the endpoint, using defaultPort when the endpoint has no port. IPv6 hosts are
+// bracketed.
+func (e controlPlaneEndpoint) URL(defaultPort int) string {
+	port := e.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	return fmt.Sprintf("https://%s", net.JoinHostPort(e.Host, strconv.Itoa(port)))
+}
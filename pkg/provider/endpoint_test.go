@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"testing"
+)
+
+func Test_parseControlPlaneHost(t *testing.T) {
+	tests := []struct {
+		in      string
+		host    string
+		port    int
+		url     string
+		wantErr bool
+	}{
+		{in: "", host: ""},
+		{in: "cluster.example.com", host: "cluster.example.com", url: "https://cluster.example.com:6443"},
+		{in: "cluster.example.com:8443", host: "cluster.example.com", port: 8443, url: "https://cluster.example.com:8443"},
+		{in: "10.0.0.1", host: "10.0.0.1", url: "https://10.0.0.1:6443"},
+		{in: "10.0.0.1:443", host: "10.0.0.1", port: 443, url: "https://10.0.0.1:443"},
+		{in: "fd00::1", host: "fd00::1", url: "https://[fd00::1]:6443"},
+		{in: "[fd00::1]", host: "fd00::1", url: "https://[fd00::1]:6443"},
+		{in: "[fd00::1]:9443", host: "fd00::1", port: 9443, url: "https://[fd00::1]:9443"},
+		{in: "https://cluster.example.com", host: "cluster.example.com", url: "https://cluster.example.com:6443"},
+		{in: "https://[fd00::1]:7443/", host: "fd00::1", port: 7443, url: "https://[fd00::1]:7443"},
+		{in: " localhost ", host: "localhost", url: "https://localhost:6443"},
+		{in: "http://cluster.example.com", wantErr: true},
+		{in: "https://cluster.example.com/api", wantErr: true},
+		{in: "cluster.example.com:0", wantErr: true},
+		{in: "cluster.example.com:port", wantErr: true},
+		{in: "fd00::1:99999]", wantErr: true},
+		{in: "bad_host!", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseControlPlaneHost(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseControlPlaneHost() = %+v, expected an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseControlPlaneHost() error = %v", err)
+			}
+			if got.Host != tt.host || got.Port != tt.port {
+				t.Errorf("parseControlPlaneHost() = %+v, want host %q port %d", got, tt.host, tt.port)
+			}
+			if tt.url != "" {
+				if url := got.URL(defaultAPIPort); url != tt.url {
+					t.Errorf("URL() = %s, want %s", url, tt.url)
+				}
+			}
+		})
+	}
+}
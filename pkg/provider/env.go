@@ -0,0 +1,42 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+// getServiceEnv renders the environment file of the k3s service: the proxy settings followed by the env section.
+func getServiceEnv(proxyValues string, env map[string]string) (string, error) {
+	if err := api.ValidateEnv(env); err != nil {
+		return "", err
+	}
+
+	var lines []string
+	if proxyValues != "" {
+		lines = append(lines, proxyValues)
+	}
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%s=%s", name, quoteEnvValue(env[name])))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// quoteEnvValue double quotes values that systemd and shells would otherwise split or expand. Both read the same
+// escapes inside double quotes.
+func quoteEnvValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\"'\\$`#;&|<>(){}*?[]~") {
+		return v
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`")
+	return `"` + r.Replace(v) + `"`
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"testing"
+)
+
+func Test_getServiceEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxy   string
+		env     map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Empty",
+			want: "",
+		},
+		{
+			name: "Env only",
+			env: map[string]string{
+				"K3S_DEBUG":            "true",
+				"CONTAINERD_LOG_LEVEL": "debug",
+				"GODEBUG":              "x509ignoreCN=0,madvdontneed=1",
+				"PATH":                 "/opt/bin:$PATH",
+				"EMPTY":                "",
+				"QUOTED":               `say "hi" \ bye`,
+			},
+			want: `CONTAINERD_LOG_LEVEL=debug
+EMPTY=""
+GODEBUG=x509ignoreCN=0,madvdontneed=1
+K3S_DEBUG=true
+PATH="/opt/bin:\$PATH"
+QUOTED="say \"hi\" \\ bye"`,
+		},
+		{
+			name:  "Proxy first",
+			proxy: "HTTP_PROXY=http://proxy:3128\nhttp_proxy=http://proxy:3128",
+			env:   map[string]string{"K3S_DEBUG": "true"},
+			want: `HTTP_PROXY=http://proxy:3128
+http_proxy=http://proxy:3128
+K3S_DEBUG=true`,
+		},
+		{
+			name:    "Invalid name",
+			env:     map[string]string{"K3S-DEBUG": "true"},
+			wantErr: true,
+		},
+		{
+			name:    "Proxy variable",
+			env:     map[string]string{"https_proxy": "http://proxy:3128"},
+			wantErr: true,
+		},
+		{
+			name:    "Multi-line value",
+			env:     map[string]string{"K3S_DEBUG": "true\nfalse"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getServiceEnv(tt.proxy, tt.env)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("getServiceEnv() = %q, expected an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getServiceEnv() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("getServiceEnv() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
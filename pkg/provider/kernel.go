@@ -0,0 +1,152 @@
+package provider
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	yip "github.com/mudler/yip/pkg/schema"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+const (
+	modulesLoadFile = "/etc/modules-load.d/k3s.conf"
+	sysctlFile      = "/etc/sysctl.d/90-k3s.conf"
+)
+
+var (
+	baseModules = []string{"overlay", "br_netfilter"}
+	ipvsModules = []string{"ip_vs", "ip_vs_rr", "ip_vs_wrr", "ip_vs_sh", "nf_conntrack"}
+
+	baseSysctls = map[string]string{
+		"net.ipv4.ip_forward":                 "1",
+		"net.bridge.bridge-nf-call-iptables":  "1",
+		"net.bridge.bridge-nf-call-ip6tables": "1",
+	}
+	// protectKernelSysctls are the values the kubelet requires with protect-kernel-defaults.
+	protectKernelSysctls = map[string]string{
+		"vm.panic_on_oom":           "0",
+		"vm.overcommit_memory":      "1",
+		"kernel.panic":              "10",
+		"kernel.panic_on_oops":      "1",
+		"kernel.keys.root_maxkeys":  "1000000",
+		"kernel.keys.root_maxbytes": "25000000",
+	}
+)
+
+// getKernelStages loads the modules and applies the sysctls k3s needs, persisting both so they also apply before
+// the provider runs on the next boot. Modules get their own stage because yip applies sysctls before loading
+// modules within a stage, and the bridge sysctls only exist once br_netfilter is loaded.
+func getKernelStages(cluster clusterplugin.Cluster, options map[string]interface{}, providerConfig api.ProviderConfig) ([]yip.Stage, error) {
+	kernel := providerConfig.Kernel
+	if err := api.ValidateKernel(kernel); err != nil {
+		return nil, err
+	}
+
+	modules, sysctls, err := getKernelDefaults(cluster, options, providerConfig.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if kernel != nil {
+		for _, m := range kernel.Modules {
+			if !slices.Contains(modules, m) {
+				modules = append(modules, m)
+			}
+		}
+		for k, v := range kernel.Sysctl {
+			sysctls[k] = v
+		}
+	}
+
+	keys := make([]string, 0, len(sysctls))
+	for k := range sysctls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var lines []string
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf("%s = %s", k, sysctls[k]))
+	}
+
+	return []yip.Stage{
+		{
+			Name: constants.LoadKernelModules,
+			Files: []yip.File{
+				{
+					Path:        modulesLoadFile,
+					Permissions: 0644,
+					Content:     strings.Join(modules, "\n") + "\n",
+				},
+			},
+			Modules: modules,
+		},
+		{
+			Name: constants.ApplySysctls,
+			Files: []yip.File{
+				{
+					Path:        sysctlFile,
+					Permissions: 0644,
+					Content:     strings.Join(lines, "\n") + "\n",
+				},
+			},
+			Sysctl: sysctls,
+		},
+	}, nil
+}
+
+// getKernelDefaults derives modules and sysctls from the flannel backend, the kube-proxy mode, dual-stack CIDRs and
+// protect-kernel-defaults, which the CIS profile turns on for server nodes.
+func getKernelDefaults(cluster clusterplugin.Cluster, clusterOptions map[string]interface{}, profile string) ([]string, map[string]string, error) {
+	options := make(map[string]interface{}, len(clusterOptions))
+	maps.Copy(options, clusterOptions)
+	for k, v := range typedProviderOptions(cluster.ProviderOptions) {
+		options[k] = v
+	}
+
+	cfg, err := decodeServerOptions(options, "flannel-backend", "disable-kube-proxy", "kube-proxy-arg", "cluster-cidr", "service-cidr", "protect-kernel-defaults")
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid cluster kernel options: %w", err)
+	}
+
+	modules := slices.Clone(baseModules)
+	sysctls := make(map[string]string)
+	for k, v := range baseSysctls {
+		sysctls[k] = v
+	}
+
+	switch cfg.FlannelBackend {
+	case "", "vxlan":
+		modules = append(modules, "vxlan")
+	case "wireguard-native":
+		modules = append(modules, "wireguard")
+	}
+	if !cfg.DisableKubeProxy && kubeProxyMode(cfg.KubeProxyArg) == "ipvs" {
+		modules = append(modules, ipvsModules...)
+	}
+	for _, cidr := range append(cfg.ClusterCIDR, cfg.ServiceCIDR...) {
+		if strings.Contains(cidr, ":") {
+			sysctls["net.ipv6.conf.all.forwarding"] = "1"
+		}
+	}
+	if cfg.ProtectKernelDefaults || (profile == api.ProfileCIS && cluster.Role != clusterplugin.RoleWorker) {
+		for k, v := range protectKernelSysctls {
+			sysctls[k] = v
+		}
+	}
+	return modules, sysctls, nil
+}
+
+func kubeProxyMode(args []string) string {
+	mode := ""
+	for _, arg := range args {
+		if v, ok := strings.CutPrefix(strings.TrimPrefix(arg, "--"), "proxy-mode="); ok {
+			mode = v
+		}
+	}
+	return mode
+}
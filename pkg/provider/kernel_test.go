@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+func Test_kernelStages(t *testing.T) {
+	tests := []struct {
+		name        string
+		cluster     clusterplugin.Cluster
+		kernel      *api.Kernel
+		profile     string
+		modules     []string
+		sysctls     map[string]string
+		sysctlsFile string
+	}{
+		{
+			name:    "Defaults",
+			cluster: clusterplugin.Cluster{Role: "init"},
+			modules: []string{"overlay", "br_netfilter", "vxlan"},
+			sysctls: map[string]string{
+				"net.ipv4.ip_forward":                 "1",
+				"net.bridge.bridge-nf-call-iptables":  "1",
+				"net.bridge.bridge-nf-call-ip6tables": "1",
+			},
+			sysctlsFile: `net.bridge.bridge-nf-call-ip6tables = 1
+net.bridge.bridge-nf-call-iptables = 1
+net.ipv4.ip_forward = 1
+`,
+		},
+		{
+			name: "Wireguard, ipvs and dual-stack",
+			cluster: clusterplugin.Cluster{
+				Role: "init",
+				Options: `flannel-backend: wireguard-native
+kube-proxy-arg: proxy-mode=ipvs
+cluster-cidr: 10.42.0.0/16,2001:cafe:42::/56`,
+			},
+			modules: []string{"overlay", "br_netfilter", "wireguard", "ip_vs", "ip_vs_rr", "ip_vs_wrr", "ip_vs_sh", "nf_conntrack"},
+			sysctls: map[string]string{
+				"net.ipv4.ip_forward":                 "1",
+				"net.bridge.bridge-nf-call-iptables":  "1",
+				"net.bridge.bridge-nf-call-ip6tables": "1",
+				"net.ipv6.conf.all.forwarding":        "1",
+			},
+		},
+		{
+			name:    "CIS profile",
+			cluster: clusterplugin.Cluster{Role: "controlplane", Options: "flannel-backend: host-gw"},
+			profile: api.ProfileCIS,
+			modules: []string{"overlay", "br_netfilter"},
+			sysctls: map[string]string{
+				"net.ipv4.ip_forward":                 "1",
+				"net.bridge.bridge-nf-call-iptables":  "1",
+				"net.bridge.bridge-nf-call-ip6tables": "1",
+				"vm.panic_on_oom":                     "0",
+				"vm.overcommit_memory":                "1",
+				"kernel.panic":                        "10",
+				"kernel.panic_on_oops":                "1",
+				"kernel.keys.root_maxkeys":            "1000000",
+				"kernel.keys.root_maxbytes":           "25000000",
+			},
+		},
+		{
+			name: "Protect kernel defaults with overrides",
+			cluster: clusterplugin.Cluster{
+				Role:            "worker",
+				Options:         "flannel-backend: none",
+				ProviderOptions: map[string]string{"protect-kernel-defaults": "true"},
+			},
+			kernel: &api.Kernel{
+				Modules: []string{"br_netfilter", "nf_conntrack"},
+				Sysctl:  map[string]string{"kernel.panic": "30", "fs.inotify.max_user_instances": "8192"},
+			},
+			modules: []string{"overlay", "br_netfilter", "nf_conntrack"},
+			sysctls: map[string]string{
+				"net.ipv4.ip_forward":                 "1",
+				"net.bridge.bridge-nf-call-iptables":  "1",
+				"net.bridge.bridge-nf-call-ip6tables": "1",
+				"vm.panic_on_oom":                     "0",
+				"vm.overcommit_memory":                "1",
+				"kernel.panic":                        "30",
+				"kernel.panic_on_oops":                "1",
+				"kernel.keys.root_maxkeys":            "1000000",
+				"kernel.keys.root_maxbytes":           "25000000",
+				"fs.inotify.max_user_instances":       "8192",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stages, err := getKernelStages(tt.cluster, mustParseClusterOptions(t, tt.cluster).values, api.ProviderConfig{Kernel: tt.kernel, Profile: tt.profile})
+			if err != nil {
+				t.Fatalf("getKernelStages() error = %v", err)
+			}
+			if len(stages) != 2 || stages[0].Name != constants.LoadKernelModules || stages[1].Name != constants.ApplySysctls {
+				t.Fatalf("unexpected stages %+v", stages)
+			}
+			if !reflect.DeepEqual(stages[0].Modules, tt.modules) {
+				t.Errorf("modules = %v, want %v", stages[0].Modules, tt.modules)
+			}
+			if !reflect.DeepEqual(stages[1].Sysctl, tt.sysctls) {
+				t.Errorf("sysctls = %v, want %v", stages[1].Sysctl, tt.sysctls)
+			}
+			if tt.sysctlsFile != "" && stages[1].Files[0].Content != tt.sysctlsFile {
+				t.Errorf("sysctl file =\n%s\nwant\n%s", stages[1].Files[0].Content, tt.sysctlsFile)
+			}
+		})
+	}
+}
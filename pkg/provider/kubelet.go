@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	yip "github.com/mudler/yip/pkg/schema"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+// kubeletConfigFile sorts after the 00-k3s-defaults.conf drop-in k3s writes, so its eviction thresholds win.
+const kubeletConfigFile = "60-provider-k3s.conf"
+
+type kubeletConfiguration struct {
+	APIVersion   string `yaml:"apiVersion"`
+	Kind         string `yaml:"kind"`
+	*api.Kubelet `yaml:",inline"`
+}
+
+// getKubeletConfigPath returns where the kubelet section is rendered. The file is a drop-in of the kubelet config
+// directory k3s manages, and is also passed with config for k3s releases that do not pass a config directory.
+func getKubeletConfigPath(cluster clusterplugin.Cluster) string {
+	return filepath.Join(getDataDir(cluster), constants.K3sKubeletConfigDir, kubeletConfigFile)
+}
+
+// getKubeletArgs points the kubelet at the rendered config, unless the cluster config passes its own.
+func getKubeletArgs(cluster clusterplugin.Cluster, kubelet *api.Kubelet, options map[string]interface{}) []string {
+	if kubelet == nil {
+		return nil
+	}
+	args := []string{"config=" + getKubeletConfigPath(cluster)}
+	return withoutUserArgs("kubelet-arg", args, options)
+}
+
+// getKubeletFiles renders the kubelet section into a KubeletConfiguration. Without the section nothing is rendered, and
+// the config stage deletes a drop-in left by an earlier one.
+func getKubeletFiles(cluster clusterplugin.Cluster, kubelet *api.Kubelet) ([]yip.File, error) {
+	if kubelet == nil {
+		return nil, nil
+	}
+	if err := api.ValidateKubelet(kubelet); err != nil {
+		return nil, err
+	}
+
+	content, err := yaml.Marshal(kubeletConfiguration{
+		APIVersion: "kubelet.config.k8s.io/v1beta1",
+		Kind:       "KubeletConfiguration",
+		Kubelet:    kubelet,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal kubelet config: %w", err)
+	}
+
+	return []yip.File{
+		{
+			Path:        getKubeletConfigPath(cluster),
+			Permissions: 0644,
+			Content:     string(content),
+		},
+	}, nil
+}
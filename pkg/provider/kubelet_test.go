@@ -0,0 +1,91 @@
+package provider
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+func Test_kubelet(t *testing.T) {
+	kubelet := &api.Kubelet{
+		MaxPods:                         intPtr(250),
+		EvictionHard:                    map[string]string{"memory.available": "200Mi", "nodefs.available": "10%"},
+		EvictionSoft:                    map[string]string{"memory.available": "500Mi"},
+		EvictionSoftGracePeriod:         map[string]string{"memory.available": "1m30s"},
+		KubeReserved:                    map[string]string{"cpu": "200m", "memory": "512Mi"},
+		SystemReserved:                  map[string]string{"memory": "1Gi"},
+		ImageGCHighThresholdPercent:     intPtr(85),
+		ImageGCLowThresholdPercent:      intPtr(70),
+		ShutdownGracePeriod:             "30s",
+		ShutdownGracePeriodCriticalPods: "10s",
+	}
+	if err := api.ValidateKubelet(kubelet); err != nil {
+		t.Fatalf("ValidateKubelet() error = %v", err)
+	}
+
+	for _, role := range []clusterplugin.Role{clusterplugin.RoleInit, clusterplugin.RoleWorker} {
+		t.Run(string(role), func(t *testing.T) {
+			cluster := clusterplugin.Cluster{Role: role, Options: "data-dir: /data/k3s"}
+
+			args := getKubeletArgs(cluster, kubelet, nil)
+			if want := "config=/data/k3s/agent/etc/kubelet.conf.d/60-provider-k3s.conf"; strings.Join(args, ",") != want {
+				t.Errorf("getKubeletArgs() = %v, want %s", args, want)
+			}
+
+			files, err := getKubeletFiles(cluster, kubelet)
+			if err != nil {
+				t.Fatalf("getKubeletFiles() error = %v", err)
+			}
+			if len(files) != 1 || files[0].Path != "/data/k3s/agent/etc/kubelet.conf.d/60-provider-k3s.conf" {
+				t.Fatalf("getKubeletFiles() = %+v", files)
+			}
+
+			var config map[string]interface{}
+			if err := yaml.Unmarshal([]byte(files[0].Content), &config); err != nil {
+				t.Fatal(err)
+			}
+			if config["kind"] != "KubeletConfiguration" || config["apiVersion"] != "kubelet.config.k8s.io/v1beta1" {
+				t.Errorf("unexpected kubelet config:\n%s", files[0].Content)
+			}
+			if config["maxPods"] != 250 || config["shutdownGracePeriod"] != "30s" {
+				t.Errorf("kubelet config does not hold the section:\n%s", files[0].Content)
+			}
+			if want := map[string]interface{}{"memory.available": "200Mi", "nodefs.available": "10%"}; !reflect.DeepEqual(config["evictionHard"], want) {
+				t.Errorf("evictionHard = %v, want %v", config["evictionHard"], want)
+			}
+		})
+	}
+
+	if args := getKubeletArgs(clusterplugin.Cluster{Role: clusterplugin.RoleWorker}, kubelet, map[string]interface{}{"kubelet-arg": []interface{}{"config=/etc/kubelet.yaml"}}); len(args) != 0 {
+		t.Errorf("getKubeletArgs() = %v, want the user config to be kept", args)
+	}
+	if files, err := getKubeletFiles(clusterplugin.Cluster{Role: clusterplugin.RoleWorker}, nil); err != nil || len(files) != 0 {
+		t.Errorf("getKubeletFiles() without a section = %v, %v", files, err)
+	}
+}
+
+func Test_kubeletDropInRemoved(t *testing.T) {
+	remove := "rm -f '/var/lib/rancher/k3s/agent/etc/kubelet.conf.d/60-provider-k3s.conf'"
+	for _, tt := range []struct {
+		name    string
+		options string
+		removed bool
+	}{
+		{name: "Section", options: "kubelet:\n  maxPods: 200", removed: false},
+		{name: "Section removed", removed: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := clusterplugin.Cluster{ClusterToken: "token", Role: clusterplugin.RoleWorker, ControlPlaneHost: "localhost", Options: tt.options}
+			commands := configFilesCommands(t, cluster)
+			if got := slices.Contains(commands, remove); got != tt.removed {
+				t.Errorf("kubelet drop-in removed = %t, want %t: %q", got, tt.removed, commands)
+			}
+		})
+	}
+}
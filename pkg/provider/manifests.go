@@ -0,0 +1,232 @@
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	yip "github.com/mudler/yip/pkg/schema"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+const (
+	defaultDataDir   = constants.K3sDataDir
+	manifestsSubDir  = constants.K3sManifestsDir
+	helmAPIVersion   = "helm.cattle.io/v1"
+	defaultChartsNS  = "kube-system"
+	manifestFileMode = 0600
+)
+
+// packagedManifests are rewritten by k3s on every start and would replace a user file of the same name.
+var packagedManifests = []string{"ccm", "coredns", "local-storage", "metrics-server", "rolebindings", "runtimes", "traefik"}
+
+var manifestNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// getManifestsStage writes the manifests and charts sections to the k3s auto-deploy directory. Workers run no
+// deploy controller, so the sections are skipped there.
+func getManifestsStage(cluster clusterplugin.Cluster, providerConfig api.ProviderConfig) (*yip.Stage, error) {
+	if len(providerConfig.Manifests) == 0 && len(providerConfig.Charts) == 0 {
+		return nil, nil
+	}
+	if cluster.Role == clusterplugin.RoleWorker {
+		logrus.Warnf("skipping manifests and charts on %s node", cluster.Role)
+		return nil, nil
+	}
+	if err := api.ValidateManifests(providerConfig.Manifests, providerConfig.Charts); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(getDataDir(cluster), manifestsSubDir)
+	seen := make(map[string]bool)
+	var files []yip.File
+
+	add := func(name string, content []byte) error {
+		if seen[name] {
+			return fmt.Errorf("manifests: more than one file is named %s", name)
+		}
+		seen[name] = true
+		files = append(files, yip.File{
+			Path:        filepath.Join(dir, name),
+			Permissions: manifestFileMode,
+			Content:     string(content),
+		})
+		return nil
+	}
+
+	for _, m := range providerConfig.Manifests {
+		if err := checkManifestName(m.Name); err != nil {
+			return nil, err
+		}
+		if slices.Contains(packagedManifests, m.Name) {
+			return nil, fmt.Errorf("manifests: %q is a k3s packaged manifest and would be overwritten on start", m.Name)
+		}
+		if err := checkManifestContent(m.Content); err != nil {
+			return nil, fmt.Errorf("manifests: %s: %w", m.Name, err)
+		}
+		if err := add(manifestFileName(m.Order, m.Name), []byte(m.Content)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, c := range providerConfig.Charts {
+		if err := checkManifestName(c.Name); err != nil {
+			return nil, err
+		}
+		content, kind, err := renderChart(c)
+		if err != nil {
+			return nil, fmt.Errorf("charts: %s: %w", c.Name, err)
+		}
+		if err := add(manifestFileName(c.Order, fmt.Sprintf("%s-%s", c.Name, strings.ToLower(kind))), content); err != nil {
+			return nil, err
+		}
+	}
+
+	return &yip.Stage{
+		Name:  constants.InstallK3sManifests,
+		Files: files,
+	}, nil
+}
+
+func manifestFileName(order *int, name string) string {
+	if order == nil {
+		return name + ".yaml"
+	}
+	return fmt.Sprintf("%02d-%s.yaml", *order, name)
+}
+
+func checkManifestName(name string) error {
+	if !manifestNamePattern.MatchString(name) {
+		return fmt.Errorf("manifests: invalid name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// checkManifestContent makes sure every document of a manifest is a YAML object, as k3s skips the whole file otherwise.
+func checkManifestContent(content string) error {
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(content)))
+	documents := 0
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if doc == nil {
+			continue
+		}
+		if _, ok := doc.(map[string]interface{}); !ok {
+			return fmt.Errorf("document %d is not a Kubernetes object", documents+1)
+		}
+		documents++
+	}
+	if documents == 0 {
+		return fmt.Errorf("no documents")
+	}
+	return nil
+}
+
+func renderChart(c api.Chart) ([]byte, string, error) {
+	kind := c.Kind
+	if kind == "" {
+		kind = api.HelmChartKind
+	}
+	namespace := c.Namespace
+	if namespace == "" {
+		namespace = defaultChartsNS
+	}
+
+	valuesContent := c.ValuesContent
+	if len(c.Values) > 0 {
+		values, err := yaml.Marshal(c.Values)
+		if err != nil {
+			return nil, "", err
+		}
+		if valuesContent != "" && !strings.HasSuffix(valuesContent, "\n") {
+			valuesContent += "\n"
+		}
+		valuesContent += string(values)
+	}
+
+	spec := map[string]interface{}{}
+	if valuesContent != "" {
+		spec["valuesContent"] = valuesContent
+	}
+	if c.FailurePolicy != "" {
+		spec["failurePolicy"] = c.FailurePolicy
+	}
+
+	switch kind {
+	case api.HelmChartKind:
+		if c.Chart == "" && c.ChartContent == "" {
+			return nil, "", fmt.Errorf("a HelmChart needs chart or chartContent")
+		}
+		for k, v := range map[string]string{
+			"chart":           c.Chart,
+			"repo":            c.Repo,
+			"repoCA":          c.RepoCA,
+			"version":         c.Version,
+			"chartContent":    c.ChartContent,
+			"targetNamespace": c.TargetNamespace,
+			"helmVersion":     c.HelmVersion,
+			"jobImage":        c.JobImage,
+			"timeout":         c.Timeout,
+		} {
+			if v != "" {
+				spec[k] = v
+			}
+		}
+		if c.CreateNamespace {
+			spec["createNamespace"] = true
+		}
+		if c.Bootstrap {
+			spec["bootstrap"] = true
+		}
+		if len(c.Set) > 0 {
+			spec["set"] = c.Set
+		}
+	case api.HelmChartConfigKind:
+		if c.Chart != "" || c.Repo != "" || c.Version != "" || c.ChartContent != "" || len(c.Set) > 0 {
+			return nil, "", fmt.Errorf("a HelmChartConfig only takes values, valuesContent and failurePolicy")
+		}
+	default:
+		return nil, "", fmt.Errorf("unknown kind %q, expected %s or %s", kind, api.HelmChartKind, api.HelmChartConfigKind)
+	}
+
+	content, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": helmAPIVersion,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      c.Name,
+			"namespace": namespace,
+		},
+		"spec": spec,
+	})
+	return content, kind, err
+}
+
+// getDataDir returns the k3s data-dir, as set in the cluster options or provider options.
+func getDataDir(cluster clusterplugin.Cluster) string {
+	if dir := cluster.ProviderOptions["data-dir"]; dir != "" {
+		return dir
+	}
+	var options struct {
+		DataDir string `yaml:"data-dir"`
+	}
+	if err := yaml.Unmarshal([]byte(cluster.Options), &options); err == nil && options.DataDir != "" {
+		return options.DataDir
+	}
+	return defaultDataDir
+}
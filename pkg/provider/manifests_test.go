@@ -0,0 +1,162 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+const manifestsOptions = `cluster-cidr: 10.42.0.0/16
+service-cidr: 10.43.0.0/16
+manifests:
+  - name: namespace
+    order: 1
+    content: |
+      apiVersion: v1
+      kind: Namespace
+      metadata:
+        name: apps
+charts:
+  - name: grafana
+    order: 10
+    repo: https://grafana.github.io/helm-charts
+    chart: grafana
+    targetNamespace: apps
+    values:
+      replicas: 2
+  - name: traefik
+    kind: HelmChartConfig
+    valuesContent: |
+      logs:
+        access:
+          enabled: true
+`
+
+func Test_manifestsStage(t *testing.T) {
+	cluster := clusterplugin.Cluster{Role: clusterplugin.RoleInit, Options: manifestsOptions}
+	stages, err := buildStages(cluster, serverSystemName, RenderOptions{})
+	if err != nil {
+		t.Fatalf("buildStages() error = %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, stage := range stages {
+		if stage.Name == constants.InstallK3sManifests {
+			for _, f := range stage.Files {
+				files[f.Path] = f.Content
+			}
+		}
+	}
+
+	want := map[string][]string{
+		"/var/lib/rancher/k3s/server/manifests/01-namespace.yaml":            {"kind: Namespace"},
+		"/var/lib/rancher/k3s/server/manifests/10-grafana-helmchart.yaml":    {"kind: HelmChart", "namespace: kube-system", "repo: https://grafana.github.io/helm-charts", "targetNamespace: apps", "replicas: 2"},
+		"/var/lib/rancher/k3s/server/manifests/traefik-helmchartconfig.yaml": {"kind: HelmChartConfig", "enabled: true"},
+	}
+	if len(files) != len(want) {
+		t.Errorf("got files %v", files)
+	}
+	for path, contains := range want {
+		content, ok := files[path]
+		if !ok {
+			t.Errorf("%s was not rendered", path)
+			continue
+		}
+		for _, s := range contains {
+			if !strings.Contains(content, s) {
+				t.Errorf("%s does not contain %q:\n%s", path, s, content)
+			}
+		}
+	}
+}
+
+func Test_manifestsStageSkippedOnWorkers(t *testing.T) {
+	cluster := clusterplugin.Cluster{Role: clusterplugin.RoleWorker, Options: manifestsOptions}
+	stages, err := buildStages(cluster, agentSystemName, RenderOptions{})
+	if err != nil {
+		t.Fatalf("buildStages() error = %v", err)
+	}
+	for _, stage := range stages {
+		if stage.Name == constants.InstallK3sManifests {
+			t.Errorf("worker renders %q", constants.InstallK3sManifests)
+		}
+	}
+}
+
+func Test_manifestsStageErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+	}{
+		{
+			name: "Packaged manifest name",
+			options: `manifests:
+  - name: traefik
+    content: "kind: ConfigMap"`,
+		},
+		{
+			name: "Invalid name",
+			options: `manifests:
+  - name: ../etc/passwd
+    content: "kind: ConfigMap"`,
+		},
+		{
+			name: "Not an object",
+			options: `manifests:
+  - name: broken
+    content: "- a\n- b"`,
+		},
+		{
+			name: "Duplicate file",
+			options: `manifests:
+  - name: app
+    content: "kind: ConfigMap"
+  - name: app
+    content: "kind: Secret"`,
+		},
+		{
+			name: "Order above 99",
+			options: `manifests:
+  - name: late
+    order: 100
+    content: "kind: ConfigMap"`,
+		},
+		{
+			name: "Chart without source",
+			options: `charts:
+  - name: app`,
+		},
+		{
+			name: "Chart config with chart",
+			options: `charts:
+  - name: app
+    kind: HelmChartConfig
+    chart: app`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := clusterplugin.Cluster{Role: clusterplugin.RoleControlPlane, Options: tt.options}
+			parsed := mustParseClusterOptions(t, cluster)
+			if _, err := getManifestsStage(cluster, parsed.providerConfig); err == nil {
+				t.Errorf("getManifestsStage() expected an error")
+			}
+		})
+	}
+}
+
+func Test_getDataDir(t *testing.T) {
+	if got := getDataDir(clusterplugin.Cluster{}); got != defaultDataDir {
+		t.Errorf("getDataDir() = %s, want %s", got, defaultDataDir)
+	}
+	if got := getDataDir(clusterplugin.Cluster{Options: "data-dir: /data/k3s"}); got != "/data/k3s" {
+		t.Errorf("getDataDir() = %s, want /data/k3s", got)
+	}
+	cluster := clusterplugin.Cluster{Options: "data-dir: /data/k3s", ProviderOptions: map[string]string{"data-dir": "/override"}}
+	if got := getDataDir(cluster); got != "/override" {
+		t.Errorf("getDataDir() = %s, want /override", got)
+	}
+}
@@ -0,0 +1,182 @@
+package provider
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	yip "github.com/mudler/yip/pkg/schema"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+// authenticationConfiguration is the apiserver.config.k8s.io AuthenticationConfiguration with a single JWT issuer.
+type authenticationConfiguration struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	JWT        []jwtAuthenticator `yaml:"jwt"`
+}
+
+type jwtAuthenticator struct {
+	Issuer               jwtIssuer             `yaml:"issuer"`
+	ClaimValidationRules []claimValidationRule `yaml:"claimValidationRules,omitempty"`
+	ClaimMappings        claimMappings         `yaml:"claimMappings"`
+}
+
+type jwtIssuer struct {
+	URL                  string   `yaml:"url"`
+	Audiences            []string `yaml:"audiences"`
+	CertificateAuthority string   `yaml:"certificateAuthority,omitempty"`
+}
+
+type claimValidationRule struct {
+	Claim         string `yaml:"claim"`
+	RequiredValue string `yaml:"requiredValue"`
+}
+
+type claimMappings struct {
+	Username prefixedClaim  `yaml:"username"`
+	Groups   *prefixedClaim `yaml:"groups,omitempty"`
+}
+
+// prefixedClaim always sets the prefix, since the API server requires it next to the claim.
+type prefixedClaim struct {
+	Claim  string `yaml:"claim"`
+	Prefix string `yaml:"prefix"`
+}
+
+// getOIDC returns the oidc section on server nodes.
+func getOIDC(cluster clusterplugin.Cluster, providerConfig api.ProviderConfig) *api.OIDC {
+	if cluster.Role == clusterplugin.RoleWorker {
+		return nil
+	}
+	return providerConfig.OIDC
+}
+
+// getOIDCKubeAPIServerArgs returns the oidc flags, or the authentication-config flag for a structured config. Flags
+// the cluster config already passes in kube-apiserver-arg are left to it, except that the oidc flags cannot be
+// combined with a structured config.
+func getOIDCKubeAPIServerArgs(cluster clusterplugin.Cluster, oidc *api.OIDC, options map[string]interface{}) ([]string, error) {
+	if oidc == nil {
+		return nil, nil
+	}
+
+	dataDir := getDataDir(cluster)
+	var args []string
+	if oidc.AuthenticationConfig {
+		for flag := range argFlags("kube-apiserver-arg", options["kube-apiserver-arg"]) {
+			if strings.HasPrefix(flag, "oidc-") {
+				return nil, fmt.Errorf("oidc: kube-apiserver-arg %s cannot be combined with authenticationConfig", flag)
+			}
+		}
+		args = append(args, "authentication-config="+filepath.Join(dataDir, constants.K3sAuthenticationConfigFile))
+	} else {
+		args = append(args,
+			"oidc-issuer-url="+oidc.IssuerURL,
+			"oidc-client-id="+oidc.ClientID,
+		)
+		for _, arg := range []struct{ flag, value string }{
+			{"oidc-username-claim", oidc.UsernameClaim},
+			{"oidc-username-prefix", oidc.UsernamePrefix},
+			{"oidc-groups-claim", oidc.GroupsClaim},
+			{"oidc-groups-prefix", oidc.GroupsPrefix},
+			{"oidc-signing-algs", strings.Join(oidc.SigningAlgs, ",")},
+		} {
+			if arg.value != "" {
+				args = append(args, arg.flag+"="+arg.value)
+			}
+		}
+		for _, claim := range sortedKeys(oidc.RequiredClaims) {
+			args = append(args, "oidc-required-claim="+claim+"="+oidc.RequiredClaims[claim])
+		}
+		if oidc.CA != "" {
+			args = append(args, "oidc-ca-file="+filepath.Join(dataDir, constants.K3sOIDCCAFile))
+		}
+	}
+
+	return withoutUserArgs("kube-apiserver-arg", args, options), nil
+}
+
+// getOIDCFiles writes the CA bundle for the oidc flags, or the structured config with the bundle inline.
+func getOIDCFiles(cluster clusterplugin.Cluster, oidc *api.OIDC) ([]yip.File, error) {
+	if oidc == nil {
+		return nil, nil
+	}
+	if err := api.ValidateOIDC(oidc); err != nil {
+		return nil, err
+	}
+
+	dataDir := getDataDir(cluster)
+	if !oidc.AuthenticationConfig {
+		if oidc.CA == "" {
+			return nil, nil
+		}
+		return []yip.File{
+			{
+				Path:        filepath.Join(dataDir, constants.K3sOIDCCAFile),
+				Permissions: 0644,
+				Content:     oidc.CA,
+			},
+		}, nil
+	}
+
+	authenticator := jwtAuthenticator{
+		Issuer: jwtIssuer{
+			URL:                  oidc.IssuerURL,
+			Audiences:            []string{oidc.ClientID},
+			CertificateAuthority: oidc.CA,
+		},
+		ClaimMappings: claimMappings{
+			Username: prefixedClaim{Claim: valueOr(oidc.UsernameClaim, "sub"), Prefix: oidcUsernamePrefix(oidc)},
+		},
+	}
+	if oidc.GroupsClaim != "" {
+		authenticator.ClaimMappings.Groups = &prefixedClaim{Claim: oidc.GroupsClaim, Prefix: oidc.GroupsPrefix}
+	}
+	for _, claim := range sortedKeys(oidc.RequiredClaims) {
+		authenticator.ClaimValidationRules = append(authenticator.ClaimValidationRules, claimValidationRule{Claim: claim, RequiredValue: oidc.RequiredClaims[claim]})
+	}
+
+	content, err := yaml.Marshal(authenticationConfiguration{
+		APIVersion: "apiserver.config.k8s.io/v1beta1",
+		Kind:       "AuthenticationConfiguration",
+		JWT:        []jwtAuthenticator{authenticator},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal authentication config: %w", err)
+	}
+
+	return []yip.File{
+		{
+			Path:        filepath.Join(dataDir, constants.K3sAuthenticationConfigFile),
+			Permissions: 0600,
+			Content:     string(content),
+		},
+	}, nil
+}
+
+// oidcUsernamePrefix applies the defaults of the oidc-username-prefix flag, which the structured config lacks.
+func oidcUsernamePrefix(oidc *api.OIDC) string {
+	switch {
+	case oidc.UsernamePrefix == "-":
+		return ""
+	case oidc.UsernamePrefix != "":
+		return oidc.UsernamePrefix
+	case oidc.UsernameClaim == "email":
+		return ""
+	}
+	return oidc.IssuerURL + "#"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+func testCA(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "issuer-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func Test_oidcKubeAPIServerArgs(t *testing.T) {
+	ca := testCA(t)
+	oidc := &api.OIDC{
+		IssuerURL:      "https://dex.example.com",
+		ClientID:       "kubernetes",
+		UsernameClaim:  "email",
+		GroupsClaim:    "groups",
+		GroupsPrefix:   "oidc:",
+		RequiredClaims: map[string]string{"hd": "example.com", "aud": "kubernetes"},
+		SigningAlgs:    []string{"RS256", "ES256"},
+		CA:             ca,
+	}
+
+	tests := []struct {
+		name     string
+		oidc     *api.OIDC
+		options  map[string]interface{}
+		wantArgs []string
+		wantErr  bool
+	}{
+		{name: "Disabled"},
+		{
+			name: "Flags",
+			oidc: oidc,
+			wantArgs: []string{
+				"oidc-issuer-url=https://dex.example.com",
+				"oidc-client-id=kubernetes",
+				"oidc-username-claim=email",
+				"oidc-groups-claim=groups",
+				"oidc-groups-prefix=oidc:",
+				"oidc-signing-algs=RS256,ES256",
+				"oidc-required-claim=aud=kubernetes",
+				"oidc-required-claim=hd=example.com",
+				"oidc-ca-file=/var/lib/rancher/k3s/server/tls/oidc-ca.crt",
+			},
+		},
+		{
+			name:    "User args",
+			oidc:    &api.OIDC{IssuerURL: "https://dex.example.com", ClientID: "kubernetes", GroupsClaim: "groups"},
+			options: map[string]interface{}{"kube-apiserver-arg": []interface{}{"oidc-client-id=other", "anonymous-auth=false"}},
+			wantArgs: []string{
+				"oidc-issuer-url=https://dex.example.com",
+				"oidc-groups-claim=groups",
+			},
+		},
+		{
+			name:     "Authentication config",
+			oidc:     &api.OIDC{IssuerURL: "https://dex.example.com", ClientID: "kubernetes", AuthenticationConfig: true},
+			options:  map[string]interface{}{"kube-apiserver-arg": []interface{}{"anonymous-auth=false"}},
+			wantArgs: []string{"authentication-config=/var/lib/rancher/k3s/server/authentication.yaml"},
+		},
+		{
+			name:    "Authentication config with oidc flags",
+			oidc:    &api.OIDC{IssuerURL: "https://dex.example.com", ClientID: "kubernetes", AuthenticationConfig: true},
+			options: map[string]interface{}{"kube-apiserver-arg": []interface{}{"oidc-groups-claim=groups"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := api.ValidateOIDC(tt.oidc); err != nil {
+				t.Fatalf("ValidateOIDC() error = %v", err)
+			}
+			args, err := getOIDCKubeAPIServerArgs(clusterplugin.Cluster{Role: clusterplugin.RoleInit}, tt.oidc, tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getOIDCKubeAPIServerArgs() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if strings.Join(args, " ") != strings.Join(tt.wantArgs, " ") {
+				t.Errorf("getOIDCKubeAPIServerArgs() = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+
+	if getOIDC(clusterplugin.Cluster{Role: clusterplugin.RoleWorker}, api.ProviderConfig{OIDC: oidc}) != nil {
+		t.Errorf("getOIDC() returned the section on a worker")
+	}
+}
+
+func Test_oidcFiles(t *testing.T) {
+	ca := testCA(t)
+	cluster := clusterplugin.Cluster{Role: clusterplugin.RoleInit}
+
+	files, err := getOIDCFiles(cluster, &api.OIDC{IssuerURL: "https://dex.example.com", ClientID: "kubernetes", CA: ca})
+	if err != nil {
+		t.Fatalf("getOIDCFiles() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "/var/lib/rancher/k3s/server/tls/oidc-ca.crt" || files[0].Content != ca {
+		t.Errorf("getOIDCFiles() = %+v", files)
+	}
+
+	files, err = getOIDCFiles(cluster, &api.OIDC{
+		IssuerURL:            "https://dex.example.com",
+		ClientID:             "kubernetes",
+		GroupsClaim:          "groups",
+		RequiredClaims:       map[string]string{"hd": "example.com"},
+		CA:                   ca,
+		AuthenticationConfig: true,
+	})
+	if err != nil {
+		t.Fatalf("getOIDCFiles() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "/var/lib/rancher/k3s/server/authentication.yaml" || files[0].Permissions != 0600 {
+		t.Fatalf("getOIDCFiles() = %+v", files)
+	}
+
+	var config authenticationConfiguration
+	if err := yaml.Unmarshal([]byte(files[0].Content), &config); err != nil {
+		t.Fatal(err)
+	}
+	want := authenticationConfiguration{
+		APIVersion: "apiserver.config.k8s.io/v1beta1",
+		Kind:       "AuthenticationConfiguration",
+		JWT: []jwtAuthenticator{
+			{
+				Issuer:               jwtIssuer{URL: "https://dex.example.com", Audiences: []string{"kubernetes"}, CertificateAuthority: ca},
+				ClaimValidationRules: []claimValidationRule{{Claim: "hd", RequiredValue: "example.com"}},
+				ClaimMappings: claimMappings{
+					Username: prefixedClaim{Claim: "sub", Prefix: "https://dex.example.com#"},
+					Groups:   &prefixedClaim{Claim: "groups"},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("authentication config = %+v, want %+v", config, want)
+	}
+}
@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	yip "github.com/mudler/yip/pkg/schema"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+// cisPodSecurity is the PodSecurity configuration of the CIS profile when no podSecurity section is given.
+var cisPodSecurity = api.PodSecurity{
+	Enforce: api.PodSecurityRestricted,
+	Audit:   api.PodSecurityRestricted,
+	Warn:    api.PodSecurityRestricted,
+	Exemptions: api.PodSecurityExemptions{
+		Namespaces: []string{"kube-system", "cis-operator-system"},
+	},
+}
+
+// admissionConfiguration is the apiserver.config.k8s.io AdmissionConfiguration holding the PodSecurity plugin config.
+type admissionConfiguration struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Plugins    []admissionPlugin `yaml:"plugins"`
+}
+
+type admissionPlugin struct {
+	Name          string                   `yaml:"name"`
+	Configuration podSecurityConfiguration `yaml:"configuration"`
+}
+
+type podSecurityConfiguration struct {
+	APIVersion string                    `yaml:"apiVersion"`
+	Kind       string                    `yaml:"kind"`
+	Defaults   map[string]string         `yaml:"defaults"`
+	Exemptions podSecurityExemptionsFile `yaml:"exemptions"`
+}
+
+// podSecurityExemptionsFile always lists every exemption, as the API server expects.
+type podSecurityExemptionsFile struct {
+	Usernames      []string `yaml:"usernames"`
+	RuntimeClasses []string `yaml:"runtimeClasses"`
+	Namespaces     []string `yaml:"namespaces"`
+}
+
+// getPodSecurity returns the PodSecurity configuration of server nodes: the podSecurity section, or the CIS defaults
+// with that profile.
+func getPodSecurity(cluster clusterplugin.Cluster, providerConfig api.ProviderConfig) *api.PodSecurity {
+	if cluster.Role == clusterplugin.RoleWorker {
+		return nil
+	}
+	if providerConfig.PodSecurity != nil {
+		return providerConfig.PodSecurity
+	}
+	if providerConfig.Profile == api.ProfileCIS {
+		podSecurity := cisPodSecurity
+		return &podSecurity
+	}
+	return nil
+}
+
+// getPodSecurityKubeAPIServerArgs points the API server at the admission config, unless the cluster config already
+// passes one in kube-apiserver-arg.
+func getPodSecurityKubeAPIServerArgs(cluster clusterplugin.Cluster, podSecurity *api.PodSecurity, options map[string]interface{}) []string {
+	if podSecurity == nil {
+		return nil
+	}
+
+	args := []string{"admission-control-config-file=" + filepath.Join(getDataDir(cluster), constants.K3sPSAConfigFile)}
+	return withoutUserArgs("kube-apiserver-arg", args, options)
+}
+
+// getPodSecurityFiles renders the AdmissionConfiguration.
+func getPodSecurityFiles(cluster clusterplugin.Cluster, podSecurity *api.PodSecurity) ([]yip.File, error) {
+	if podSecurity == nil {
+		return nil, nil
+	}
+	if err := api.ValidatePodSecurity(podSecurity); err != nil {
+		return nil, err
+	}
+
+	defaults := make(map[string]string)
+	for _, mode := range []struct{ name, level, version string }{
+		{"enforce", podSecurity.Enforce, podSecurity.EnforceVersion},
+		{"audit", podSecurity.Audit, podSecurity.AuditVersion},
+		{"warn", podSecurity.Warn, podSecurity.WarnVersion},
+	} {
+		defaults[mode.name] = valueOr(mode.level, api.PodSecurityPrivileged)
+		defaults[mode.name+"-version"] = valueOr(mode.version, "latest")
+	}
+
+	content, err := yaml.Marshal(admissionConfiguration{
+		APIVersion: "apiserver.config.k8s.io/v1",
+		Kind:       "AdmissionConfiguration",
+		Plugins: []admissionPlugin{
+			{
+				Name: "PodSecurity",
+				Configuration: podSecurityConfiguration{
+					APIVersion: "pod-security.admission.config.k8s.io/v1",
+					Kind:       "PodSecurityConfiguration",
+					Defaults:   defaults,
+					Exemptions: podSecurityExemptionsFile{
+						Usernames:      nonNil(podSecurity.Exemptions.Usernames),
+						RuntimeClasses: nonNil(podSecurity.Exemptions.RuntimeClasses),
+						Namespaces:     nonNil(podSecurity.Exemptions.Namespaces),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal PodSecurity admission config: %w", err)
+	}
+
+	return []yip.File{
+		{
+			Path:        filepath.Join(getDataDir(cluster), constants.K3sPSAConfigFile),
+			Permissions: 0600,
+			Content:     string(content),
+		},
+	}, nil
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// nonNil keeps empty lists in the rendered file rather than null.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+func Test_podSecurity(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           clusterplugin.Role
+		providerConfig api.ProviderConfig
+		options        map[string]interface{}
+		wantArgs       []string
+		wantDefaults   map[string]interface{}
+		wantNamespaces []interface{}
+	}{
+		{
+			name: "Disabled",
+			role: clusterplugin.RoleInit,
+		},
+		{
+			name: "Section",
+			role: clusterplugin.RoleControlPlane,
+			providerConfig: api.ProviderConfig{PodSecurity: &api.PodSecurity{
+				Enforce:        api.PodSecurityBaseline,
+				EnforceVersion: "v1.29",
+				Warn:           api.PodSecurityRestricted,
+				Exemptions:     api.PodSecurityExemptions{Namespaces: []string{"monitoring"}},
+			}},
+			wantArgs: []string{"admission-control-config-file=/var/lib/rancher/k3s/server/psa.yaml"},
+			wantDefaults: map[string]interface{}{
+				"enforce": "baseline", "enforce-version": "v1.29",
+				"audit": "privileged", "audit-version": "latest",
+				"warn": "restricted", "warn-version": "latest",
+			},
+			wantNamespaces: []interface{}{"monitoring"},
+		},
+		{
+			name:           "CIS profile",
+			role:           clusterplugin.RoleInit,
+			providerConfig: api.ProviderConfig{Profile: api.ProfileCIS},
+			wantArgs:       []string{"admission-control-config-file=/var/lib/rancher/k3s/server/psa.yaml"},
+			wantDefaults: map[string]interface{}{
+				"enforce": "restricted", "enforce-version": "latest",
+				"audit": "restricted", "audit-version": "latest",
+				"warn": "restricted", "warn-version": "latest",
+			},
+			wantNamespaces: []interface{}{"kube-system", "cis-operator-system"},
+		},
+		{
+			name:           "User admission config",
+			role:           clusterplugin.RoleInit,
+			providerConfig: api.ProviderConfig{PodSecurity: &api.PodSecurity{}},
+			options: map[string]interface{}{
+				"kube-apiserver-arg": []interface{}{"admission-control-config-file=/etc/k3s/admission.yaml"},
+			},
+			wantDefaults: map[string]interface{}{
+				"enforce": "privileged", "enforce-version": "latest",
+				"audit": "privileged", "audit-version": "latest",
+				"warn": "privileged", "warn-version": "latest",
+			},
+			wantNamespaces: []interface{}{},
+		},
+		{
+			name:           "Worker",
+			role:           clusterplugin.RoleWorker,
+			providerConfig: api.ProviderConfig{PodSecurity: &api.PodSecurity{}, Profile: api.ProfileCIS},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := api.ValidatePodSecurity(tt.providerConfig.PodSecurity); err != nil {
+				t.Fatalf("ValidatePodSecurity() error = %v", err)
+			}
+			cluster := clusterplugin.Cluster{Role: tt.role}
+			podSecurity := getPodSecurity(cluster, tt.providerConfig)
+
+			if args := getPodSecurityKubeAPIServerArgs(cluster, podSecurity, tt.options); strings.Join(args, ",") != strings.Join(tt.wantArgs, ",") {
+				t.Errorf("getPodSecurityKubeAPIServerArgs() = %v, want %v", args, tt.wantArgs)
+			}
+
+			files, err := getPodSecurityFiles(cluster, podSecurity)
+			if err != nil {
+				t.Fatalf("getPodSecurityFiles() error = %v", err)
+			}
+			if tt.wantDefaults == nil {
+				if len(files) != 0 {
+					t.Errorf("getPodSecurityFiles() = %+v, want none", files)
+				}
+				return
+			}
+			if len(files) != 1 || files[0].Path != "/var/lib/rancher/k3s/server/psa.yaml" || files[0].Permissions != 0600 {
+				t.Fatalf("getPodSecurityFiles() = %+v", files)
+			}
+
+			var admission struct {
+				Kind    string `yaml:"kind"`
+				Plugins []struct {
+					Name          string `yaml:"name"`
+					Configuration struct {
+						Defaults   map[string]interface{} `yaml:"defaults"`
+						Exemptions map[string]interface{} `yaml:"exemptions"`
+					} `yaml:"configuration"`
+				} `yaml:"plugins"`
+			}
+			if err := yaml.Unmarshal([]byte(files[0].Content), &admission); err != nil {
+				t.Fatal(err)
+			}
+			if admission.Kind != "AdmissionConfiguration" || len(admission.Plugins) != 1 || admission.Plugins[0].Name != "PodSecurity" {
+				t.Fatalf("unexpected admission config:\n%s", files[0].Content)
+			}
+			configuration := admission.Plugins[0].Configuration
+			if !reflect.DeepEqual(configuration.Defaults, tt.wantDefaults) {
+				t.Errorf("defaults = %v, want %v", configuration.Defaults, tt.wantDefaults)
+			}
+			if !reflect.DeepEqual(configuration.Exemptions["namespaces"], tt.wantNamespaces) {
+				t.Errorf("exempt namespaces = %v, want %v", configuration.Exemptions["namespaces"], tt.wantNamespaces)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	yip "github.com/mudler/yip/pkg/schema"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+const (
+	kubeletPort    = 10250
+	etcdClientPort = 2379
+	etcdPeerPort   = 2380
+)
+
+// getPreflightStage checks the host once the config is in place and before the service is started. Failures are
+// only reported unless the preflight section blocks startup. binary is the plugin binary that runs the checks.
+func getPreflightStage(cluster clusterplugin.Cluster, options map[string]interface{}, cfg *api.Preflight, binary string) (yip.Stage, error) {
+	if err := api.ValidatePreflight(cfg); err != nil {
+		return yip.Stage{}, err
+	}
+
+	ports, err := getPreflightPorts(cluster, options)
+	if err != nil {
+		return yip.Stage{}, err
+	}
+
+	command := fmt.Sprintf("%s %s --role %s --data-dir %s --ports %s --output %s",
+		binary, constants.PreflightCommand, cluster.Role, getDataDir(cluster), joinPorts(ports), constants.PreflightReport)
+	if cfg != nil {
+		if len(cfg.Skip) > 0 {
+			command += " --skip " + strings.Join(cfg.Skip, ",")
+		}
+		if cfg.Block {
+			command += " --block"
+		}
+	}
+
+	return yip.Stage{
+		Name:     constants.RunK3sPreflight,
+		Commands: []string{command},
+	}, nil
+}
+
+// getPreflightPorts returns the ports k3s binds for the role. Servers also run embedded etcd unless an external
+// datastore is configured.
+func getPreflightPorts(cluster clusterplugin.Cluster, options map[string]interface{}) ([]int, error) {
+	if cluster.Role == clusterplugin.RoleWorker {
+		return []int{kubeletPort}, nil
+	}
+
+	apiPort, err := getAPIPort(cluster, options)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := []int{apiPort, kubeletPort}
+	_, external := options[constants.DatastoreEndpoint]
+	if _, ok := cluster.ProviderOptions[constants.DatastoreEndpoint]; ok {
+		external = true
+	}
+	if !external {
+		ports = append(ports, etcdClientPort, etcdPeerPort)
+	}
+	return ports, nil
+}
+
+// startCondition adds the pre-flight result to the condition of a service start stage when failures block startup.
+func startCondition(condition string, cfg *api.Preflight) string {
+	if cfg == nil || !cfg.Block {
+		return condition
+	}
+	return fmt.Sprintf("%s && [ ! -e %s ]", condition, constants.PreflightFailed)
+}
+
+func joinPorts(ports []int) string {
+	s := make([]string, 0, len(ports))
+	for _, p := range ports {
+		s = append(s, strconv.Itoa(p))
+	}
+	return strings.Join(s, ",")
+}
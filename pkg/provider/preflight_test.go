@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+func Test_preflightStage(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster clusterplugin.Cluster
+		cfg     *api.Preflight
+		want    string
+	}{
+		{
+			name:    "Init",
+			cluster: clusterplugin.Cluster{Role: "init"},
+			want:    "/usr/bin/agent-provider-k3s preflight --role init --data-dir /var/lib/rancher/k3s --ports 6443,10250,2379,2380 --output /run/provider-k3s/preflight.json",
+		},
+		{
+			name: "Control plane with external datastore",
+			cluster: clusterplugin.Cluster{
+				Role: "controlplane",
+				Options: `https-listen-port: 7443
+datastore-endpoint: postgres://db:5432/k3s
+data-dir: /data/k3s`,
+			},
+			cfg:  &api.Preflight{Block: true, Skip: []string{"clock", "modules"}},
+			want: "/usr/bin/agent-provider-k3s preflight --role controlplane --data-dir /data/k3s --ports 7443,10250 --output /run/provider-k3s/preflight.json --skip clock,modules --block",
+		},
+		{
+			name:    "Worker",
+			cluster: clusterplugin.Cluster{Role: "worker"},
+			want:    "/usr/bin/agent-provider-k3s preflight --role worker --data-dir /var/lib/rancher/k3s --ports 10250 --output /run/provider-k3s/preflight.json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage, err := getPreflightStage(tt.cluster, mustParseClusterOptions(t, tt.cluster).values, tt.cfg, "/usr/bin/agent-provider-k3s")
+			if err != nil {
+				t.Fatalf("getPreflightStage() error = %v", err)
+			}
+			if len(stage.Commands) != 1 || stage.Commands[0] != tt.want {
+				t.Errorf("getPreflightStage() commands = %q, want %q", stage.Commands, tt.want)
+			}
+		})
+	}
+
+	if _, err := getPreflightStage(clusterplugin.Cluster{Role: "init"}, nil, &api.Preflight{Skip: []string{"dns"}}, "/usr/bin/agent-provider-k3s"); err == nil {
+		t.Errorf("getPreflightStage() expected an error for an unknown check")
+	}
+}
+
+func Test_startCondition(t *testing.T) {
+	if got := startCondition("[ -x /bin/systemctl ]", &api.Preflight{}); got != "[ -x /bin/systemctl ]" {
+		t.Errorf("startCondition() = %s", got)
+	}
+	want := "[ -x /bin/systemctl ] && [ ! -e /run/provider-k3s/preflight.failed ]"
+	if got := startCondition("[ -x /bin/systemctl ]", &api.Preflight{Block: true}); got != want {
+		t.Errorf("startCondition() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package provider
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	yip "github.com/mudler/yip/pkg/schema"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+// profileConfigFile sorts before 90_userdata.yaml, so the cluster config is merged over the profile.
+const profileConfigFile = "80_profile.yaml"
+
+var cisTLSCipherSuites = []string{
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305",
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305",
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+}
+
+// getProfileFiles renders the profile into a config drop-in. Settings the cluster config or provider options already
+// set are left out of the drop-in, so users can override them one by one. Auditing and PodSecurity admission are
+// rendered by getAudit and getPodSecurity. Without a profile, or on workers, nothing is rendered and the config stage
+// deletes a drop-in left by an earlier profile.
+func getProfileFiles(cluster clusterplugin.Cluster, options map[string]interface{}, providerConfig api.ProviderConfig) ([]yip.File, error) {
+	profile := providerConfig.Profile
+	if profile == "" {
+		return nil, nil
+	}
+	if err := api.ValidateProfile(profile); err != nil {
+		return nil, err
+	}
+	if cluster.Role == clusterplugin.RoleWorker {
+		logrus.Warnf("profile %s is only applied to server nodes, skipping", profile)
+		return nil, nil
+	}
+
+	user := make(map[string]interface{}, len(options))
+	maps.Copy(user, options)
+	for k, v := range cluster.ProviderOptions {
+		user[k] = v
+	}
+
+	profileConfig := cisConfig()
+	if providerConfig.Encryption != nil {
+		// The encryption section supplies the keys instead of k3s.
+		delete(profileConfig, "secrets-encryption")
+	}
+	content, err := yaml.Marshal(withoutUserSettings(profileConfig, user))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal profile %s: %w", profile, err)
+	}
+
+	return []yip.File{
+		{
+			Path:        filepath.Join(configurationPath, profileConfigFile),
+			Permissions: 0400,
+			Content:     string(content),
+		},
+	}, nil
+}
+
+func cisConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"protect-kernel-defaults": true,
+		"secrets-encryption":      true,
+		"kube-apiserver-arg": []string{
+			"enable-admission-plugins=NodeRestriction",
+		},
+		"kube-controller-manager-arg": []string{
+			"terminated-pod-gc-threshold=10",
+		},
+		"kubelet-arg": []string{
+			"streaming-connection-idle-timeout=5m",
+			"tls-cipher-suites=" + strings.Join(cisTLSCipherSuites, ","),
+		},
+	}
+}
+
+// withoutUserSettings drops the profile keys the user sets, and the entries of argument lists whose flag the user
+// passes in the same list.
+func withoutUserSettings(profile, user map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for key, value := range profile {
+		userValue, set := user[key]
+		args, isList := value.([]string)
+		if !set {
+			out[key] = value
+			continue
+		}
+		if !isList {
+			continue
+		}
+
+		userFlags := argFlags(key, userValue)
+		var kept []string
+		for _, arg := range args {
+			if !userFlags[argFlag(arg)] {
+				kept = append(kept, arg)
+			}
+		}
+		if len(kept) > 0 {
+			out[key] = kept
+		}
+	}
+	return out
+}
+
+// withoutUserArgs drops the arguments whose flag the user passes in the argument list option key.
+func withoutUserArgs(key string, args []string, options map[string]interface{}) []string {
+	kept, _ := withoutUserSettings(map[string]interface{}{key: args}, options)[key].([]string)
+	return kept
+}
+
+// argFlags returns the flags passed in the argument list option key.
+func argFlags(key string, value interface{}) map[string]bool {
+	flags := make(map[string]bool)
+	switch v := decodeOption(key, value).(type) {
+	case []string:
+		for _, arg := range v {
+			flags[argFlag(arg)] = true
+		}
+	case []interface{}:
+		for _, arg := range v {
+			flags[argFlag(fmt.Sprint(arg))] = true
+		}
+	}
+	return flags
+}
+
+func argFlag(arg string) string {
+	flag, _, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+	return flag
+}
@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+func Test_profileFiles(t *testing.T) {
+	cluster := clusterplugin.Cluster{
+		Role: "init",
+		Options: `data-dir: /data/k3s
+secrets-encryption: false
+kubelet-arg:
+  - streaming-connection-idle-timeout=1h
+  - max-pods=200
+profile: cis`,
+	}
+
+	files, err := getProfileFiles(cluster, mustParseClusterOptions(t, cluster).values, api.ProviderConfig{Profile: api.ProfileCIS})
+	if err != nil {
+		t.Fatalf("getProfileFiles() error = %v", err)
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	wantPaths := []string{"/etc/rancher/k3s/config.d/80_profile.yaml"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Fatalf("getProfileFiles() paths = %v, want %v", paths, wantPaths)
+	}
+
+	var cfg map[string]interface{}
+	if err := yaml.Unmarshal([]byte(files[0].Content), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cfg["secrets-encryption"]; ok {
+		t.Errorf("profile overrides secrets-encryption set by the user")
+	}
+	if cfg["protect-kernel-defaults"] != true {
+		t.Errorf("profile does not set protect-kernel-defaults")
+	}
+	wantKubeletArgs := []interface{}{"tls-cipher-suites=" + "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"}
+	if !reflect.DeepEqual(cfg["kubelet-arg"], wantKubeletArgs) {
+		t.Errorf("kubelet-arg = %v, want %v", cfg["kubelet-arg"], wantKubeletArgs)
+	}
+	wantAPIServerArgs := []interface{}{
+		"enable-admission-plugins=NodeRestriction",
+	}
+	if !reflect.DeepEqual(cfg["kube-apiserver-arg"], wantAPIServerArgs) {
+		t.Errorf("kube-apiserver-arg = %v, want %v", cfg["kube-apiserver-arg"], wantAPIServerArgs)
+	}
+}
+
+func Test_profileFilesSkipped(t *testing.T) {
+	if files, err := getProfileFiles(clusterplugin.Cluster{Role: "init"}, nil, api.ProviderConfig{}); err != nil || len(files) != 0 {
+		t.Errorf("getProfileFiles() without a profile = %v, %v", files, err)
+	}
+	if files, err := getProfileFiles(clusterplugin.Cluster{Role: "worker"}, nil, api.ProviderConfig{Profile: api.ProfileCIS}); err != nil || len(files) != 0 {
+		t.Errorf("getProfileFiles() on a worker = %v, %v", files, err)
+	}
+	if _, err := getProfileFiles(clusterplugin.Cluster{Role: "init"}, nil, api.ProviderConfig{Profile: "stig"}); err == nil {
+		t.Errorf("getProfileFiles() expected an error for an unknown profile")
+	}
+}
+
+func Test_profileFilesRemoved(t *testing.T) {
+	remove := "rm -f '/etc/rancher/k3s/config.d/80_profile.yaml'"
+	for _, tt := range []struct {
+		name    string
+		role    clusterplugin.Role
+		options string
+		removed bool
+	}{
+		{name: "Profile", role: clusterplugin.RoleInit, options: "profile: cis", removed: false},
+		{name: "Profile removed", role: clusterplugin.RoleInit, removed: true},
+		{name: "Worker", role: clusterplugin.RoleWorker, options: "profile: cis", removed: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := clusterplugin.Cluster{ClusterToken: "token", ControlPlaneHost: "localhost", Role: tt.role, Options: tt.options}
+			commands := configFilesCommands(t, cluster)
+			if got := slices.Contains(commands, remove); got != tt.removed {
+				t.Errorf("profile drop-in removed = %t, want %t: %q", got, tt.removed, commands)
+			}
+		})
+	}
+}
@@ -3,9 +3,12 @@ package provider
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
+	"reflect"
 	"slices"
+	"strconv"
 
-	"net"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -19,10 +22,12 @@ import (
 
 	"github.com/kairos-io/provider-k3s/api"
 	"github.com/kairos-io/provider-k3s/pkg/constants"
+	"github.com/kairos-io/provider-k3s/pkg/log"
+	"github.com/kairos-io/provider-k3s/pkg/validation"
 )
 
 const (
-	configurationPath       = "/etc/rancher/k3s/config.d"
+	configurationPath       = constants.K3sConfigDir
 	containerdEnvConfigPath = "/etc/default"
 	localImagesPath         = "/opt/content/images"
 
@@ -33,83 +38,183 @@ const (
 	k8sNoProxy = ".svc,.svc.cluster,.svc.cluster.local"
 )
 
+// RenderOptions changes how the config is rendered, so it can be previewed on a host that is not the node. The zero
+// value renders the config for the node the plugin runs on.
+type RenderOptions struct {
+	// ProviderBinary is the path stages use to call back into the plugin binary. When empty, the path of the running
+	// executable is used.
+	ProviderBinary string
+	// NodeAddresses, when not nil, replaces the addresses of the node interfaces added to NO_PROXY.
+	NodeAddresses []string
+	// SkipHostState skips the checks against the state k3s left under the data dir, such as the encryption keys in use.
+	SkipHostState bool
+}
+
 func ClusterProvider(cluster clusterplugin.Cluster) yip.YipConfig {
+	cfg, err := BuildConfig(cluster, RenderOptions{})
+	if err != nil {
+		logrus.Errorf("failed to generate k3s configuration: %s", err)
+		cfg = newConfig([]yip.Stage{getConfigErrorStage(err)})
+	}
+
+	return cfg
+}
+
+// BuildConfig renders the yip configuration for the cluster, returning an error instead of a failing stage when the
+// cluster section is invalid.
+func BuildConfig(cluster clusterplugin.Cluster, opts RenderOptions) (yip.YipConfig, error) {
 	logrus.Infof("current node role %s", cluster.Role)
 	logrus.Infof("received cluster env %+v", cluster.Env)
-	logrus.Infof("received cluster options %s", cluster.Options)
+	logrus.Infof("received cluster options %s", loggedOptions(cluster.Options))
 
-	systemName := serverSystemName
-	if cluster.Role == clusterplugin.RoleWorker {
-		systemName = agentSystemName
+	logValidationIssues(cluster)
+
+	stages, err := buildStages(cluster, getSystemName(cluster), opts)
+	if err != nil {
+		return yip.YipConfig{}, err
 	}
 
-	cfg := yip.YipConfig{
+	return newConfig(stages), nil
+}
+
+// logValidationIssues warns about config keys that k3s would silently drop. Invalid YAML is left for parseOptions to
+// report.
+func logValidationIssues(cluster clusterplugin.Cluster) {
+	issues, err := validation.Validate(cluster)
+	if err != nil {
+		return
+	}
+	for _, issue := range issues {
+		logrus.Warnf("cluster config %s", issue)
+	}
+}
+
+func newConfig(stages []yip.Stage) yip.YipConfig {
+	return yip.YipConfig{
 		Name: "K3s Kairos Cluster Provider",
 		Stages: map[string][]yip.Stage{
-			bootBefore: parseStages(cluster, parseFiles(cluster, systemName), systemName),
+			bootBefore: stages,
 		},
 	}
+}
 
-	return cfg
+func getSystemName(cluster clusterplugin.Cluster) string {
+	if cluster.Role == clusterplugin.RoleWorker {
+		return agentSystemName
+	}
+	return serverSystemName
+}
+
+func buildStages(cluster clusterplugin.Cluster, systemName string, opts RenderOptions) ([]yip.Stage, error) {
+	parsed, err := parseClusterOptions(cluster)
+	if err != nil {
+		return nil, err
+	}
+	files, err := parseFiles(cluster, systemName, parsed, opts)
+	if err != nil {
+		return nil, err
+	}
+	return parseStages(cluster, files, systemName, parsed, opts)
 }
 
-func parseOptions(cluster clusterplugin.Cluster) ([]byte, []byte, []byte) {
+// getConfigErrorStage replaces the whole stage set when the cluster section cannot be rendered. No configuration is
+// written and the k3s service is never enabled, so k3s cannot start on a partial config; the stage fails so the
+// problem is visible on the console and in the yip output.
+func getConfigErrorStage(err error) yip.Stage {
+	return yip.Stage{
+		Name: constants.K3sConfigError,
+		Commands: []string{
+			fmt.Sprintf("echo %s >&2", shellQuote(log.Redact(fmt.Sprintf("provider-k3s: invalid cluster configuration: %s", err)))),
+			"exit 1",
+		},
+	}
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func parseOptions(cluster clusterplugin.Cluster, parsed clusterOptions) ([]byte, []byte, []byte, error) {
 	k3sConfig := &api.K3sServerConfig{
 		Token: cluster.ClusterToken,
 	}
-	logrus.Printf("cluster Options: %s\n", cluster.Options)
+	logrus.Printf("cluster Options: %s\n", loggedOptions(cluster.Options))
 
-	var configYaml map[string]interface{}
-	if err := yaml.Unmarshal([]byte(cluster.Options), &configYaml); err != nil {
-		logrus.Fatalf("failed to un-marshal cluster options %s", err)
-	}
+	configYaml := removeProviderSections(maps.Clone(parsed.values))
 	configYaml = decodeOptions(configYaml) // Convert all list of strings in the input struct presented as a string, to a comma separated string
 	userOptionConfig, err := yaml.Marshal(configYaml)
 	if err != nil {
-		logrus.Fatalf("failed to marshal userOptionConfig %s", err)
+		return nil, nil, nil, fmt.Errorf("failed to marshal userOptionConfig: %w", err)
+	}
+
+	endpoint, err := parseControlPlaneHost(cluster.ControlPlaneHost)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	apiPort, err := getAPIPort(cluster, configYaml)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
 	switch cluster.Role {
 	case clusterplugin.RoleInit, clusterplugin.RoleControlPlane:
 		k3sConfig.ClusterInit = cluster.Role == clusterplugin.RoleInit
-		if cluster.Role == clusterplugin.RoleControlPlane {
-			k3sConfig.Server = fmt.Sprintf("https://%s:6443", cluster.ControlPlaneHost)
+		if cluster.Role == clusterplugin.RoleControlPlane && endpoint.Host != "" {
+			k3sConfig.Server = endpoint.URL(apiPort)
+		}
+		if endpoint.Host != "" {
+			k3sConfig.TLSSan = []string{endpoint.Host}
 		}
-		k3sConfig.TLSSan = []string{cluster.ControlPlaneHost}
 		// Data received from upstream contains config for both control plane and worker. Thus, for control plane,
 		// config is being filtered via unmarshal into server config.
 		var serverCfg api.K3sServerConfig
-		if err := yaml.Unmarshal(userOptionConfig, &serverCfg); err == nil {
-			userOptionConfig, _ = yaml.Marshal(serverCfg)
-		} else {
-			logrus.Fatalf("failed to un-marshal cluster options into k3s server config interface %s", err)
+		if err := yaml.Unmarshal(userOptionConfig, &serverCfg); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to un-marshal cluster options into k3s server config: %w", err)
 		}
+		userOptionConfig, _ = yaml.Marshal(serverCfg)
 	case clusterplugin.RoleWorker:
-		k3sConfig.Server = fmt.Sprintf("https://%s:6443", cluster.ControlPlaneHost)
+		if endpoint.Host != "" {
+			k3sConfig.Server = endpoint.URL(apiPort)
+		}
 		// Data received from upstream contains config for both control plane and worker. Thus, for worker,
 		// config is being filtered via unmarshal into agent config.
 		var agentCfg api.K3sAgentConfig
-		if err := yaml.Unmarshal(userOptionConfig, &agentCfg); err == nil {
-			userOptionConfig, _ = yaml.Marshal(agentCfg)
-		} else {
-			logrus.Fatalf("failed to un-marshal cluster options in k3s agent config %s", err)
+		if err := yaml.Unmarshal(userOptionConfig, &agentCfg); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to un-marshal cluster options into k3s agent config: %w", err)
 		}
+		userOptionConfig, _ = yaml.Marshal(agentCfg)
+	}
+
+	providerConfig := parsed.providerConfig
+	k3sConfig.KubeletArg = append(k3sConfig.KubeletArg, getSwapKubeletArgs(providerConfig.Swap)...)
+	k3sConfig.KubeletArg = append(k3sConfig.KubeletArg, getKubeletArgs(cluster, providerConfig.Kubelet, configYaml)...)
+	k3sConfig.KubeApiServerArg = append(k3sConfig.KubeApiServerArg, getAuditKubeAPIServerArgs(cluster, getAudit(cluster, providerConfig), configYaml)...)
+	k3sConfig.KubeApiServerArg = append(k3sConfig.KubeApiServerArg, getPodSecurityKubeAPIServerArgs(cluster, getPodSecurity(cluster, providerConfig), configYaml)...)
+	encryptionArgs, err := getEncryptionKubeAPIServerArgs(cluster, getEncryption(cluster, providerConfig), configYaml)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	k3sConfig.KubeApiServerArg = append(k3sConfig.KubeApiServerArg, encryptionArgs...)
+	oidcArgs, err := getOIDCKubeAPIServerArgs(cluster, getOIDC(cluster, providerConfig), configYaml)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	k3sConfig.KubeApiServerArg = append(k3sConfig.KubeApiServerArg, oidcArgs...)
 
 	userOptions, _ := kyaml.YAMLToJSON(userOptionConfig)
-	proxyOptions, _ := kyaml.YAMLToJSON([]byte(cluster.Options))
+	proxyOptions, _ := json.Marshal(parsed.values)
 	options, _ := json.Marshal(k3sConfig)
 
 	// if provided, parse additional K3s server options (which may override the above settings)
 	if len(cluster.ProviderOptions) > 0 {
 		logrus.Infof("applying cluster provider options: %+v", cluster.ProviderOptions)
 
-		providerOpts, err := yaml.Marshal(cluster.ProviderOptions)
+		providerOpts, err := yaml.Marshal(typedProviderOptions(cluster.ProviderOptions))
 		if err != nil {
-			logrus.Fatalf("failed to marshal cluster.ProviderOptions: %v", err)
+			return nil, nil, nil, fmt.Errorf("failed to marshal cluster.ProviderOptions: %w", err)
 		}
 		if err := yaml.Unmarshal(providerOpts, k3sConfig); err != nil {
-			logrus.Fatalf("failed to unmarshal cluster.ProviderOptions: %v", err)
+			return nil, nil, nil, fmt.Errorf("failed to unmarshal cluster.ProviderOptions: %w", err)
 		}
 		options, _ = json.Marshal(k3sConfig)
 
@@ -121,11 +226,14 @@ func parseOptions(cluster clusterplugin.Cluster) ([]byte, []byte, []byte) {
 		}
 	}
 
-	return options, proxyOptions, userOptions
+	return options, proxyOptions, userOptions, nil
 }
 
-func parseFiles(cluster clusterplugin.Cluster, systemName string) []yip.File {
-	options, proxyOptions, userOptions := parseOptions(cluster)
+func parseFiles(cluster clusterplugin.Cluster, systemName string, parsed clusterOptions, opts RenderOptions) ([]yip.File, error) {
+	options, proxyOptions, userOptions, err := parseOptions(cluster, parsed)
+	if err != nil {
+		return nil, err
+	}
 
 	files := []yip.File{
 		{
@@ -140,34 +248,109 @@ func parseFiles(cluster clusterplugin.Cluster, systemName string) []yip.File {
 		},
 	}
 
-	proxyValues := proxyEnv(proxyOptions, cluster.Env)
+	providerConfig := parsed.providerConfig
+	profileFiles, err := getProfileFiles(cluster, parsed.values, providerConfig)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, profileFiles...)
+
+	auditFiles, err := getAuditFiles(cluster, getAudit(cluster, providerConfig))
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, auditFiles...)
+
+	podSecurityFiles, err := getPodSecurityFiles(cluster, getPodSecurity(cluster, providerConfig))
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, podSecurityFiles...)
+
+	encryptionFiles, err := getEncryptionFiles(cluster, getEncryption(cluster, providerConfig), opts.SkipHostState)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, encryptionFiles...)
+
+	oidcFiles, err := getOIDCFiles(cluster, getOIDC(cluster, providerConfig))
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, oidcFiles...)
+
+	files = append(files, getSwapFiles(cluster, providerConfig.Swap)...)
 
+	kubeletFiles, err := getKubeletFiles(cluster, providerConfig.Kubelet)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, kubeletFiles...)
+
+	registriesFiles, err := getRegistriesFiles(providerConfig.Registries)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, registriesFiles...)
+
+	proxyValues, err := proxyEnv(proxyOptions, cluster.Env, getNoProxyInterfaces(cluster), opts.NodeAddresses)
+	if err != nil {
+		return nil, err
+	}
 	if len(proxyValues) > 0 {
 		logrus.Infof("setting proxy values %s", proxyValues)
+	}
+
+	serviceEnv, err := getServiceEnv(proxyValues, providerConfig.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(serviceEnv) > 0 {
 		files = append(files, yip.File{
 			Path:        filepath.Join(containerdEnvConfigPath, systemName),
 			Permissions: 0400,
-			Content:     proxyValues,
+			Content:     serviceEnv,
 		})
 	}
 
-	return files
+	return files, nil
 }
 
-func parseStages(cluster clusterplugin.Cluster, files []yip.File, systemName string) []yip.Stage {
+func parseStages(cluster clusterplugin.Cluster, files []yip.File, systemName string, parsed clusterOptions, opts RenderOptions) ([]yip.Stage, error) {
 	var stages []yip.Stage
 	clusterRootPath := getClusterRootPath(cluster)
+	providerConfig := parsed.providerConfig
 
-	stages = append(stages, getSwapDisableStage())
+	if err := api.ValidateSwap(providerConfig.Swap); err != nil {
+		return nil, err
+	}
+	if swapStage := getSwapStage(providerConfig.Swap); swapStage != nil {
+		stages = append(stages, *swapStage)
+	}
+
+	kernelStages, err := getKernelStages(cluster, parsed.values, providerConfig)
+	if err != nil {
+		return nil, err
+	}
+	stages = append(stages, kernelStages...)
 
 	stages = append(stages, yip.Stage{
 		Name:  constants.InstallK3sConfigFiles,
 		Files: files,
-		Commands: []string{
-			fmt.Sprintf("jq -s 'def flatten: reduce .[] as $i([]; if $i | type == \"array\" then . + ($i | flatten) else . + [$i] end); [.[] | to_entries] | flatten | reduce .[] as $dot ({}; .[$dot.key] += $dot.value)' %s/*.yaml > /etc/rancher/k3s/config.yaml", configurationPath),
-		},
+		Commands: append(getStaleFileCommands(cluster, files),
+			fmt.Sprintf("%s %s --dir %s --output %s", opts.providerBinary(), constants.MergeConfigCommand, configurationPath, constants.K3sConfigFile),
+		),
 	})
 
+	manifestsStage, err := getManifestsStage(cluster, providerConfig)
+	if err != nil {
+		return nil, err
+	}
+	if manifestsStage != nil {
+		stages = append(stages, *manifestsStage)
+	}
+
 	if cluster.ImportLocalImages {
 		if cluster.LocalImagesPath == "" {
 			cluster.LocalImagesPath = localImagesPath
@@ -175,17 +358,37 @@ func parseStages(cluster clusterplugin.Cluster, files []yip.File, systemName str
 		importStage := yip.Stage{
 			Name: constants.ImportK3sImages,
 			Commands: []string{
-				fmt.Sprintf("chmod +x %s/opt/k3s/scripts/import.sh", clusterRootPath),
-				fmt.Sprintf("/bin/sh %s/opt/k3s/scripts/import.sh %s > /var/log/k3s-import-images.log", clusterRootPath, filepath.Join(clusterRootPath, cluster.LocalImagesPath)),
+				fmt.Sprintf("%s %s --path %s --images-dir %s > /var/log/k3s-import-images.log", opts.providerBinary(), constants.ImportImagesCommand, filepath.Join(clusterRootPath, cluster.LocalImagesPath), filepath.Join(getDataDir(cluster), constants.K3sImagesDir)),
 			},
 		}
 		stages = append(stages, importStage)
+
+		if cluster.Role != clusterplugin.RoleWorker {
+			stages = append(stages, yip.Stage{
+				Name: constants.ImportK3sContent,
+				Commands: []string{
+					fmt.Sprintf("%s %s --path %s --data-dir %s", opts.providerBinary(), constants.ImportContentCommand, filepath.Join(clusterRootPath, cluster.LocalImagesPath), getDataDir(cluster)),
+				},
+			})
+		}
+	}
+
+	preflightStage, err := getPreflightStage(cluster, parsed.values, providerConfig.Preflight, opts.providerBinary())
+	if err != nil {
+		return nil, err
+	}
+	stages = append(stages, preflightStage)
+
+	serviceStages, err := getServiceStages(providerConfig.Service, systemName)
+	if err != nil {
+		return nil, err
 	}
+	stages = append(stages, serviceStages...)
 
 	stages = append(stages,
 		yip.Stage{
 			Name: constants.EnableOpenRCServices,
-			If:   "[ -x /sbin/openrc-run ]",
+			If:   startCondition("[ -x /sbin/openrc-run ]", providerConfig.Preflight),
 			Commands: []string{
 				fmt.Sprintf("rc-update add %s default >/dev/null", systemName),
 				fmt.Sprintf("service %s start", systemName),
@@ -193,7 +396,7 @@ func parseStages(cluster clusterplugin.Cluster, files []yip.File, systemName str
 		},
 		yip.Stage{
 			Name: constants.EnableSystemdServices,
-			If:   "[ -x /bin/systemctl ]",
+			If:   startCondition("[ -x /bin/systemctl ]", providerConfig.Preflight),
 			Commands: []string{
 				// A /run link is cleared each boot, so systemd can never start k3s before this stage renders config.yaml.
 				// Not `systemctl disable`: on UKI the unit is a symlink into /opt/k8s, which disable deletes.
@@ -207,100 +410,93 @@ func parseStages(cluster clusterplugin.Cluster, files []yip.File, systemName str
 		},
 	)
 
-	return stages
+	return stages, nil
 }
 
-func getSwapDisableStage() yip.Stage {
-	return yip.Stage{
-		Name: "disable disk swap",
-		Commands: []string{
-			"sed -i '/ swap / s/^\\(.*\\)$/#\\1/g' /etc/fstab",
-			"swapoff -a",
-		},
-	}
-}
-
-func proxyEnv(proxyOptions []byte, proxyMap map[string]string) string {
-	var proxy []string
-	var noProxy string
-	var isProxyConfigured bool
-
-	httpProxy := proxyMap["HTTP_PROXY"]
-	httpsProxy := proxyMap["HTTPS_PROXY"]
-	userNoProxy := proxyMap["NO_PROXY"]
-
-	defaultNoProxy := getDefaultNoProxy(proxyOptions)
-	logrus.Infof("setting default no proxy to %s", defaultNoProxy)
-
-	if len(httpProxy) > 0 {
-		proxy = append(proxy, fmt.Sprintf("HTTP_PROXY=%s", httpProxy))
-		proxy = append(proxy, fmt.Sprintf("CONTAINERD_HTTP_PROXY=%s", httpProxy))
-		isProxyConfigured = true
-	}
-
-	if len(httpsProxy) > 0 {
-		proxy = append(proxy, fmt.Sprintf("HTTPS_PROXY=%s", httpsProxy))
-		proxy = append(proxy, fmt.Sprintf("CONTAINERD_HTTPS_PROXY=%s", httpsProxy))
-		isProxyConfigured = true
+// getStaleFileCommands deletes the files the provider writes into persistent directories that are not rendered for
+// this boot. They were left by a section that has since been removed or changed, and would otherwise keep applying.
+func getStaleFileCommands(cluster clusterplugin.Cluster, files []yip.File) []string {
+	rendered := make(map[string]bool, len(files))
+	for _, f := range files {
+		rendered[f.Path] = true
 	}
 
-	if isProxyConfigured {
-		noProxy = defaultNoProxy
+	var commands []string
+	for _, path := range []string{
+		getSwapKubeletConfigPath(cluster),
+		filepath.Join(configurationPath, profileConfigFile),
+		getKubeletConfigPath(cluster),
+	} {
+		if !rendered[path] {
+			commands = append(commands, "rm -f "+shellQuote(path))
+		}
 	}
+	return commands
+}
 
-	if len(userNoProxy) > 0 {
-		noProxy = noProxy + "," + userNoProxy
+// providerBinary returns the path of the plugin binary, which stages call back into for subcommands.
+func (opts RenderOptions) providerBinary() string {
+	if opts.ProviderBinary != "" {
+		return opts.ProviderBinary
 	}
-
-	if len(noProxy) > 0 {
-		proxy = append(proxy, fmt.Sprintf("NO_PROXY=%s", noProxy))
-		proxy = append(proxy, fmt.Sprintf("CONTAINERD_NO_PROXY=%s", noProxy))
+	if path, err := os.Executable(); err == nil {
+		return path
 	}
-
-	return strings.Join(proxy, "\n")
+	return constants.ProviderBinary
 }
 
-func getDefaultNoProxy(proxyOptions []byte) string {
-	var noProxy string
+func getClusterRootPath(cluster clusterplugin.Cluster) string {
+	return cluster.ProviderOptions[constants.ClusterRootPath]
+}
 
-	data := make(map[string]interface{})
-	err := json.Unmarshal(proxyOptions, &data)
+// decodeServerOptions decodes only the given keys of the cluster options into the server config, so a mistyped
+// unrelated option is left for parseOptions to report.
+func decodeServerOptions(options map[string]interface{}, keys ...string) (api.K3sServerConfig, error) {
+	subset := make(map[string]interface{})
+	for _, key := range keys {
+		if v, ok := options[key]; ok && v != nil {
+			subset[key] = decodeOption(key, v)
+		}
+	}
+	raw, err := json.Marshal(subset)
 	if err != nil {
-		logrus.Fatalf("error while unmarshalling user options %s", err)
+		return api.K3sServerConfig{}, err
 	}
 
-	if data != nil {
-		clusterCIDR := data["cluster-cidr"].(string)
-		serviceCIDR := data["service-cidr"].(string)
+	var cfg api.K3sServerConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return api.K3sServerConfig{}, err
+	}
+	return cfg, nil
+}
 
-		if len(clusterCIDR) > 0 {
-			noProxy = noProxy + "," + clusterCIDR
-		}
-		if len(serviceCIDR) > 0 {
-			noProxy = noProxy + "," + serviceCIDR
+// typedProviderOptions converts the provider options whose k3s config field is an integer or a boolean, so they can
+// be unmarshalled into the typed fields instead of being quoted as strings. Every other option is kept as given.
+func typedProviderOptions(in map[string]string) map[string]interface{} {
+	kinds := make(map[string]reflect.Kind)
+	for _, f := range api.ServerFields() {
+		t := f.Type
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
 		}
+		kinds[f.Key] = t.Kind()
 	}
-	noProxy = noProxy + "," + getNodeCIDR() + "," + k8sNoProxy
-
-	return noProxy
-}
 
-func getNodeCIDR() string {
-	addrs, _ := net.InterfaceAddrs()
-	var result string
-	for _, addr := range addrs {
-		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
-			if ipnet.IP.To4() != nil {
-				result = addr.String()
-				break
+	out := make(map[string]interface{}, len(in))
+	for k, v := range in {
+		out[k] = v
+		switch kinds[k] {
+		case reflect.Int:
+			if i, err := strconv.Atoi(v); err == nil {
+				out[k] = i
+			}
+		case reflect.Bool:
+			if b, err := strconv.ParseBool(v); err == nil {
+				out[k] = b
 			}
 		}
 	}
-	return result
-}
-
-func getClusterRootPath(cluster clusterplugin.Cluster) string {
-	return cluster.ProviderOptions[constants.ClusterRootPath]
+	return out
 }
 
 func decodeOptions(in map[string]interface{}) map[string]interface{} {
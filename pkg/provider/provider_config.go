@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+// clusterOptions are the cluster options, parsed once per build and shared by every stage.
+type clusterOptions struct {
+	// values are the options as parsed from YAML, provider sections included. They are shared, so callers that
+	// change them work on a copy.
+	values         map[string]interface{}
+	providerConfig api.ProviderConfig
+}
+
+func parseClusterOptions(cluster clusterplugin.Cluster) (clusterOptions, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal([]byte(cluster.Options), &values); err != nil {
+		return clusterOptions{}, fmt.Errorf("failed to un-marshal cluster options: %w", err)
+	}
+	providerConfig, err := api.ParseProviderConfig(values)
+	if err != nil {
+		return clusterOptions{}, err
+	}
+	return clusterOptions{values: values, providerConfig: providerConfig}, nil
+}
+
+// removeProviderSections drops the provider sections so they never reach the k3s config.
+func removeProviderSections(options map[string]interface{}) map[string]interface{} {
+	for k := range options {
+		if api.IsProviderConfigKey(k) {
+			delete(options, k)
+		}
+	}
+	return options
+}
+
+// loggedOptions returns the cluster options for the log. Provider sections hold credentials, keys and certificates
+// in shapes the key based redaction cannot follow, such as block scalars, so only their names are logged.
+func loggedOptions(options string) string {
+	var parsed map[string]interface{}
+	if err := yaml.Unmarshal([]byte(options), &parsed); err != nil {
+		return "(invalid YAML, not logged)"
+	}
+
+	var sections []string
+	for k := range parsed {
+		if api.IsProviderConfigKey(k) {
+			sections = append(sections, k)
+		}
+	}
+	out, err := yaml.Marshal(removeProviderSections(parsed))
+	if err != nil {
+		return "(not logged)"
+	}
+	if len(sections) == 0 {
+		return string(out)
+	}
+	slices.Sort(sections)
+	return fmt.Sprintf("%sprovider sections: %s", out, strings.Join(sections, ", "))
+}
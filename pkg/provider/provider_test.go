@@ -77,6 +77,94 @@ func Test_parseOptions(t *testing.T) {
 			expectedProxyOptions: []byte(`null`),
 			expectedUserOptions:  []byte(`{}`),
 		},
+		{
+			name: "Control Plane: IPv6",
+			cluster: clusterplugin.Cluster{
+				ClusterToken:     "token",
+				ControlPlaneHost: "fd00::10",
+				Role:             "controlplane",
+			},
+			expectedOptions:      []byte(`{"tls-san":["fd00::10"],"token":"token","server":"https://[fd00::10]:6443"}`),
+			expectedProxyOptions: []byte(`null`),
+			expectedUserOptions:  []byte(`{}`),
+		},
+		{
+			name: "Worker: URL with port",
+			cluster: clusterplugin.Cluster{
+				ClusterToken:     "token",
+				ControlPlaneHost: "https://[fd00::10]:8443",
+				Role:             "worker",
+			},
+			expectedOptions:      []byte(`{"token":"token","server":"https://[fd00::10]:8443"}`),
+			expectedProxyOptions: []byte(`null`),
+			expectedUserOptions:  []byte(`{}`),
+		},
+		{
+			name: "Control Plane: https-listen-port",
+			cluster: clusterplugin.Cluster{
+				ClusterToken:     "token",
+				ControlPlaneHost: "localhost",
+				Role:             "controlplane",
+				Options:          "https-listen-port: 7443",
+			},
+			expectedOptions:      []byte(`{"tls-san":["localhost"],"token":"token","server":"https://localhost:7443"}`),
+			expectedProxyOptions: []byte(`{"https-listen-port":7443}`),
+			expectedUserOptions:  []byte(`{"https-listen-port":7443}`),
+		},
+		{
+			name: "Worker: https-listen-port provider option",
+			cluster: clusterplugin.Cluster{
+				ClusterToken:     "token",
+				ControlPlaneHost: "localhost",
+				Role:             "worker",
+				Options:          "https-listen-port: 7443",
+				ProviderOptions: map[string]string{
+					"https-listen-port": "9443",
+				},
+			},
+			expectedOptions:      []byte(`{"https-listen-port":9443,"token":"token","server":"https://localhost:9443"}`),
+			expectedProxyOptions: []byte(`{"https-listen-port":7443}`),
+			expectedUserOptions:  []byte(`{}`),
+		},
+		{
+			name: "Control Plane: write-kubeconfig-mode provider option",
+			cluster: clusterplugin.Cluster{
+				ClusterToken:     "token",
+				ControlPlaneHost: "localhost",
+				Role:             "controlplane",
+				ProviderOptions: map[string]string{
+					"write-kubeconfig-mode": "0644",
+				},
+			},
+			expectedOptions:      []byte(`{"tls-san":["localhost"],"write-kubeconfig-mode":"0644","token":"token","server":"https://localhost:6443"}`),
+			expectedProxyOptions: []byte(`null`),
+			expectedUserOptions:  []byte(`{}`),
+		},
+		{
+			name: "Worker: control plane host port wins",
+			cluster: clusterplugin.Cluster{
+				ClusterToken:     "token",
+				ControlPlaneHost: "lb.example.com:443",
+				Role:             "worker",
+				Options:          "https-listen-port: 7443",
+			},
+			expectedOptions:      []byte(`{"token":"token","server":"https://lb.example.com:443"}`),
+			expectedProxyOptions: []byte(`{"https-listen-port":7443}`),
+			expectedUserOptions:  []byte(`{}`),
+		},
+		{
+			name: "Worker: swap allowed",
+			cluster: clusterplugin.Cluster{
+				ClusterToken:     "token",
+				ControlPlaneHost: "localhost",
+				Role:             "worker",
+				Options: `swap:
+  policy: allow`,
+			},
+			expectedOptions:      []byte(`{"token":"token","server":"https://localhost:6443","kubelet-arg":["fail-swap-on=false"]}`),
+			expectedProxyOptions: []byte(`{"swap":{"policy":"allow"}}`),
+			expectedUserOptions:  []byte(`{}`),
+		},
 		{
 			name: "Control Plane: With Options",
 			cluster: clusterplugin.Cluster{
@@ -93,7 +181,10 @@ enable-pprof: true`,
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			options, proxyOptions, userOptions := parseOptions(tt.cluster)
+			options, proxyOptions, userOptions, err := parseOptions(tt.cluster, mustParseClusterOptions(t, tt.cluster))
+			if err != nil {
+				t.Fatalf("parseOptions() error = %v", err)
+			}
 			if !bytes.Equal(options, tt.expectedOptions) {
 				t.Errorf("parseOptions() options = %v, want %v", string(options), string(tt.expectedOptions))
 			}
@@ -107,6 +198,116 @@ enable-pprof: true`,
 	}
 }
 
+// mustParseClusterOptions parses the cluster options as buildStages does.
+func mustParseClusterOptions(t *testing.T, cluster clusterplugin.Cluster) clusterOptions {
+	t.Helper()
+	parsed, err := parseClusterOptions(cluster)
+	if err != nil {
+		t.Fatalf("parseClusterOptions() error = %v", err)
+	}
+	return parsed
+}
+
+func Test_parseOptionsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster clusterplugin.Cluster
+	}{
+		{
+			name: "Invalid YAML",
+			cluster: clusterplugin.Cluster{
+				Role:    "init",
+				Options: "node-name: [unterminated",
+			},
+		},
+		{
+			name: "Server type mismatch",
+			cluster: clusterplugin.Cluster{
+				Role:    "controlplane",
+				Options: "https-listen-port: not-a-port",
+			},
+		},
+		{
+			name: "Agent type mismatch",
+			cluster: clusterplugin.Cluster{
+				Role:    "worker",
+				Options: "lb-server-port: not-a-port",
+			},
+		},
+		{
+			name: "Invalid control plane host",
+			cluster: clusterplugin.Cluster{
+				Role:             "worker",
+				ControlPlaneHost: "http://cluster.example.com",
+			},
+		},
+		{
+			name: "Invalid provider options",
+			cluster: clusterplugin.Cluster{
+				Role: "init",
+				ProviderOptions: map[string]string{
+					"https-listen-port": "not-a-port",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parseClusterOptions(tt.cluster)
+			if err == nil {
+				_, _, _, err = parseOptions(tt.cluster, parsed)
+			}
+			if err == nil {
+				t.Errorf("parseOptions() expected an error")
+			}
+		})
+	}
+}
+
+func Test_clusterProviderConfigError(t *testing.T) {
+	cfg := ClusterProvider(clusterplugin.Cluster{
+		Role:    "init",
+		Options: "node-name: [unterminated",
+	})
+
+	stages := cfg.Stages[bootBefore]
+	if len(stages) != 1 || stages[0].Name != constants.K3sConfigError {
+		t.Fatalf("expected a single %q stage, got %+v", constants.K3sConfigError, stages)
+	}
+	joined := strings.Join(stages[0].Commands, "\n")
+	if !strings.Contains(joined, "failed to un-marshal cluster options") {
+		t.Errorf("error stage does not report the problem:\n%s", joined)
+	}
+	if !strings.Contains(joined, "exit 1") {
+		t.Errorf("error stage does not fail:\n%s", joined)
+	}
+}
+
+func Test_buildStagesInvalidSections(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    clusterplugin.Role
+		options string
+		wantErr string
+	}{
+		{name: "Kernel", role: "worker", options: "kernel: {modules: [\"ip_vs; reboot\"]}", wantErr: `kernel: invalid module name "ip_vs; reboot"`},
+		{name: "Swap", role: "worker", options: "swap: {policy: off}", wantErr: `swap: invalid policy "off"`},
+		{name: "Audit", role: "init", options: "audit: {level: Everything}", wantErr: `audit: invalid level "Everything"`},
+		{name: "PodSecurity", role: "init", options: "podSecurity: {enforce: strict}", wantErr: `podSecurity: invalid enforce level "strict"`},
+		{name: "Encryption", role: "init", options: "encryption: {keys: [{name: key1, secret: c2hvcnQ=}]}", wantErr: `encryption: key "key1" must be 16, 24 or 32 bytes for aescbc, got 5`},
+		{name: "OIDC", role: "init", options: "oidc: {issuerURL: http://dex.example.com, clientID: kubernetes}", wantErr: `oidc: issuerURL "http://dex.example.com" must be an https URL`},
+		{name: "Kubelet", role: "worker", options: "kubelet: {maxPods: 0}", wantErr: "kubelet: maxPods must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := clusterplugin.Cluster{Role: tt.role, Options: "data-dir: " + t.TempDir() + "\n" + tt.options}
+			if _, err := buildStages(cluster, getSystemName(cluster), RenderOptions{}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("buildStages() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func Test_unmarshall(t *testing.T) {
 	yamlfile := `test: "xyz,zyx"
 `
@@ -209,8 +410,13 @@ func Test_systemdStageDoesNotPersistEnablement(t *testing.T) {
 				systemName = agentSystemName
 			}
 
+			stages, err := buildStages(cluster, systemName, RenderOptions{})
+			if err != nil {
+				t.Fatalf("buildStages() error = %v", err)
+			}
+
 			var commands []string
-			for _, stage := range parseStages(cluster, parseFiles(cluster, systemName), systemName) {
+			for _, stage := range stages {
 				if stage.Name == constants.EnableSystemdServices {
 					commands = stage.Commands
 				}
@@ -235,3 +441,101 @@ func Test_systemdStageDoesNotPersistEnablement(t *testing.T) {
 		})
 	}
 }
+
+func Test_configFilesStageUsesProviderMerge(t *testing.T) {
+	cluster := clusterplugin.Cluster{ClusterToken: "token", ControlPlaneHost: "localhost", Role: clusterplugin.RoleInit}
+	stages, err := buildStages(cluster, serverSystemName, RenderOptions{})
+	if err != nil {
+		t.Fatalf("buildStages() error = %v", err)
+	}
+
+	for _, stage := range stages {
+		if stage.Name != constants.InstallK3sConfigFiles {
+			continue
+		}
+		joined := strings.Join(stage.Commands, "\n")
+		if strings.Contains(joined, "jq ") {
+			t.Errorf("stage still depends on jq:\n%s", joined)
+		}
+		if !strings.Contains(joined, fmt.Sprintf("%s %s", RenderOptions{}.providerBinary(), constants.MergeConfigCommand)) {
+			t.Errorf("stage does not call the provider merge subcommand:\n%s", joined)
+		}
+		return
+	}
+	t.Fatalf("no %q stage found", constants.InstallK3sConfigFiles)
+}
+
+// configFilesCommands returns the commands of the config files stage built for the cluster.
+func configFilesCommands(t *testing.T, cluster clusterplugin.Cluster) []string {
+	t.Helper()
+	stages, err := buildStages(cluster, serverSystemName, RenderOptions{})
+	if err != nil {
+		t.Fatalf("buildStages() error = %v", err)
+	}
+	for _, stage := range stages {
+		if stage.Name == constants.InstallK3sConfigFiles {
+			return stage.Commands
+		}
+	}
+	t.Fatalf("no %q stage found", constants.InstallK3sConfigFiles)
+	return nil
+}
+
+func Test_importContentStage(t *testing.T) {
+	for _, role := range []clusterplugin.Role{clusterplugin.RoleInit, clusterplugin.RoleWorker} {
+		t.Run(string(role), func(t *testing.T) {
+			cluster := clusterplugin.Cluster{
+				ClusterToken:      "token",
+				ControlPlaneHost:  "localhost",
+				Role:              role,
+				ImportLocalImages: true,
+				LocalImagesPath:   "/opt/content",
+			}
+			stages, err := buildStages(cluster, getSystemName(cluster), RenderOptions{})
+			if err != nil {
+				t.Fatalf("buildStages() error = %v", err)
+			}
+
+			var commands []string
+			for _, stage := range stages {
+				if stage.Name == constants.ImportK3sContent {
+					commands = stage.Commands
+				}
+			}
+			if role == clusterplugin.RoleWorker {
+				if commands != nil {
+					t.Errorf("worker imports charts and manifests: %v", commands)
+				}
+				return
+			}
+			want := fmt.Sprintf("%s %s --path /opt/content --data-dir /var/lib/rancher/k3s", RenderOptions{}.providerBinary(), constants.ImportContentCommand)
+			if len(commands) != 1 || commands[0] != want {
+				t.Errorf("stage commands = %v, want %q", commands, want)
+			}
+		})
+	}
+}
+
+func Test_importImagesStage(t *testing.T) {
+	cluster := clusterplugin.Cluster{
+		Role:              clusterplugin.RoleWorker,
+		ImportLocalImages: true,
+		ProviderOptions:   map[string]string{constants.ClusterRootPath: "/opt/k8s"},
+	}
+	stages, err := buildStages(cluster, agentSystemName, RenderOptions{})
+	if err != nil {
+		t.Fatalf("buildStages() error = %v", err)
+	}
+	for _, stage := range stages {
+		if stage.Name != constants.ImportK3sImages {
+			continue
+		}
+		joined := strings.Join(stage.Commands, "\n")
+		want := fmt.Sprintf("%s %s --path /opt/k8s/opt/content/images --images-dir /var/lib/rancher/k3s/agent/images", RenderOptions{}.providerBinary(), constants.ImportImagesCommand)
+		if !strings.Contains(joined, want) {
+			t.Errorf("stage does not call the Go importer:\n%s", joined)
+		}
+		return
+	}
+	t.Fatalf("no %q stage found", constants.ImportK3sImages)
+}
@@ -0,0 +1,228 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+// k3s defaults, used when the cluster config does not set them.
+const (
+	defaultClusterCIDR   = "10.42.0.0/16"
+	defaultServiceCIDR   = "10.43.0.0/16"
+	defaultClusterDomain = "cluster.local"
+)
+
+// proxyVariables are the proxy environment variables read from the cluster env. Each is written to the k3s env file
+// in upper and lower case, since tools disagree on which one they read, and with the CONTAINERD_ prefix for the
+// embedded containerd.
+var proxyVariables = []string{"HTTP_PROXY", "HTTPS_PROXY"}
+
+// nodeInterface is a network interface of the node with the addresses assigned to it.
+type nodeInterface struct {
+	Name  string
+	Addrs []net.Addr
+}
+
+// listInterfaces returns the up, non-loopback interfaces of the node. Tests replace it.
+var listInterfaces = func() ([]nodeInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []nodeInterface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, nodeInterface{Name: iface.Name, Addrs: addrs})
+	}
+	return out, nil
+}
+
+// proxyEnv renders the proxy variables of the service environment. nodeAddresses, when not nil, replaces the addresses
+// of the node interfaces in the default NO_PROXY.
+func proxyEnv(proxyOptions []byte, env map[string]string, interfaces, nodeAddresses []string) (string, error) {
+	var proxy []string
+	var noProxy string
+
+	for _, name := range proxyVariables {
+		if value := lookupProxyEnv(env, name); value != "" {
+			proxy = appendProxyEnv(proxy, name, value)
+		}
+	}
+
+	if len(proxy) > 0 {
+		defaultNoProxy, err := getDefaultNoProxy(proxyOptions, interfaces, nodeAddresses)
+		if err != nil {
+			return "", err
+		}
+		logrus.Infof("setting default no proxy to %s", defaultNoProxy)
+		noProxy = defaultNoProxy
+	}
+
+	noProxy = joinNoProxy(splitNoProxy(noProxy), splitNoProxy(env["NO_PROXY"]), splitNoProxy(env["no_proxy"]))
+	if len(noProxy) > 0 {
+		proxy = appendProxyEnv(proxy, "NO_PROXY", noProxy)
+	}
+
+	return strings.Join(proxy, "\n"), nil
+}
+
+// lookupProxyEnv returns the value of the proxy variable, preferring the upper case name over the lower case one.
+func lookupProxyEnv(env map[string]string, name string) string {
+	if value := env[name]; value != "" {
+		return value
+	}
+	return env[strings.ToLower(name)]
+}
+
+func appendProxyEnv(proxy []string, name, value string) []string {
+	return append(proxy,
+		fmt.Sprintf("%s=%s", name, value),
+		fmt.Sprintf("%s=%s", strings.ToLower(name), value),
+		fmt.Sprintf("CONTAINERD_%s=%s", name, value),
+	)
+}
+
+func getNoProxyInterfaces(cluster clusterplugin.Cluster) []string {
+	return splitNoProxy(cluster.ProviderOptions[constants.NoProxyInterfaces])
+}
+
+// getDefaultNoProxy lists what must bypass the proxy for the cluster to work: the pod and service CIDRs, the node
+// addresses of both families and the in-cluster service domains.
+func getDefaultNoProxy(proxyOptions []byte, interfaces, nodeAddresses []string) (string, error) {
+	cfg, err := getNetworkConfig(proxyOptions)
+	if err != nil {
+		return "", err
+	}
+
+	var entries []string
+	for _, cidr := range append(cfg.ClusterCIDR, cfg.ServiceCIDR...) {
+		entries = append(entries, splitNoProxy(cidr)...)
+	}
+
+	if nodeAddresses == nil {
+		nodeAddresses, err = getNodeAddresses(interfaces)
+		if err != nil {
+			return "", err
+		}
+	}
+	entries = append(entries, nodeAddresses...)
+	entries = append(entries, splitNoProxy(k8sNoProxy)...)
+	entries = append(entries, ".svc."+cfg.ClusterDomain, "."+cfg.ClusterDomain)
+
+	return joinNoProxy(entries), nil
+}
+
+// getNetworkConfig reads the CIDRs and cluster domain from the cluster options, falling back to the k3s defaults.
+func getNetworkConfig(proxyOptions []byte) (api.K3sServerConfig, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(proxyOptions, &data); err != nil {
+		return api.K3sServerConfig{}, fmt.Errorf("error while unmarshalling user options: %w", err)
+	}
+
+	cfg, err := decodeServerOptions(data, "cluster-cidr", "service-cidr", "cluster-domain")
+	if err != nil {
+		return api.K3sServerConfig{}, fmt.Errorf("invalid cluster network options: %w", err)
+	}
+	if len(cfg.ClusterCIDR) == 0 {
+		cfg.ClusterCIDR = []string{defaultClusterCIDR}
+	}
+	if len(cfg.ServiceCIDR) == 0 {
+		cfg.ServiceCIDR = []string{defaultServiceCIDR}
+	}
+	if cfg.ClusterDomain == "" {
+		cfg.ClusterDomain = defaultClusterDomain
+	}
+	return cfg, nil
+}
+
+// getNodeAddresses returns the IPv4 and IPv6 addresses of the interfaces selected by patterns, in CIDR notation.
+// Loopback and link-local addresses are skipped, as they never reach the proxy.
+func getNodeAddresses(patterns []string) ([]string, error) {
+	ifaces, err := listInterfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	var out []string
+	for _, iface := range ifaces {
+		selected, err := interfaceSelected(iface.Name, patterns)
+		if err != nil {
+			return nil, err
+		}
+		if !selected {
+			continue
+		}
+		for _, addr := range iface.Addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			out = append(out, ipnet.String())
+		}
+	}
+	return out, nil
+}
+
+// interfaceSelected reports whether name matches the interface filter. Patterns use filepath.Match syntax and those
+// starting with ! exclude interfaces. Without include patterns every interface that is not excluded is selected.
+func interfaceSelected(name string, patterns []string) (bool, error) {
+	included, hasIncludes := false, false
+	for _, pattern := range patterns {
+		exclude := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		matched, err := filepath.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid %s pattern %q: %w", constants.NoProxyInterfaces, pattern, err)
+		}
+		if exclude {
+			if matched {
+				return false, nil
+			}
+			continue
+		}
+		hasIncludes = true
+		included = included || matched
+	}
+	return included || !hasIncludes, nil
+}
+
+func splitNoProxy(s string) []string {
+	var out []string
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+// joinNoProxy joins the entry lists into a NO_PROXY value, keeping the first occurrence of each entry.
+func joinNoProxy(lists ...[]string) string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, list := range lists {
+		for _, entry := range list {
+			if !seen[entry] {
+				seen[entry] = true
+				out = append(out, entry)
+			}
+		}
+	}
+	return strings.Join(out, ",")
+}
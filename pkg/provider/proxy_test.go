@@ -0,0 +1,191 @@
+package provider
+
+import (
+	"net"
+	"testing"
+)
+
+func fakeInterfaces(t *testing.T, ifaces map[string][]string) {
+	t.Helper()
+	orig := listInterfaces
+	t.Cleanup(func() { listInterfaces = orig })
+
+	var out []nodeInterface
+	for _, name := range []string{"lo", "eth0", "eth1", "cni0", "wg0"} {
+		cidrs, ok := ifaces[name]
+		if !ok {
+			continue
+		}
+		iface := nodeInterface{Name: name}
+		for _, cidr := range cidrs {
+			ip, ipnet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ipnet.IP = ip
+			iface.Addrs = append(iface.Addrs, ipnet)
+		}
+		out = append(out, iface)
+	}
+	listInterfaces = func() ([]nodeInterface, error) { return out, nil }
+}
+
+func Test_getDefaultNoProxy(t *testing.T) {
+	fakeInterfaces(t, map[string][]string{
+		"lo":   {"127.0.0.1/8", "::1/128"},
+		"eth0": {"192.168.1.10/24", "fe80::1/64", "fd00::10/64"},
+		"eth1": {"10.0.0.5/16"},
+		"cni0": {"10.42.0.1/24"},
+	})
+
+	tests := []struct {
+		name       string
+		options    string
+		interfaces []string
+		want       string
+	}{
+		{
+			name:    "No options",
+			options: `null`,
+			want:    "10.42.0.0/16,10.43.0.0/16,192.168.1.10/24,fd00::10/64,10.0.0.5/16,10.42.0.1/24,.svc,.svc.cluster,.svc.cluster.local,.cluster.local",
+		},
+		{
+			name:       "Options without CIDRs",
+			options:    `{"node-name":"node","service-cidr":"10.96.0.0/12"}`,
+			interfaces: []string{"eth0"},
+			want:       "10.42.0.0/16,10.96.0.0/12,192.168.1.10/24,fd00::10/64,.svc,.svc.cluster,.svc.cluster.local,.cluster.local",
+		},
+		{
+			name:       "CIDR lists",
+			options:    `{"cluster-cidr":["10.42.0.0/16","2001:cafe:42::/56"],"service-cidr":["10.43.0.0/16"]}`,
+			interfaces: []string{"eth1"},
+			want:       "10.42.0.0/16,2001:cafe:42::/56,10.43.0.0/16,10.0.0.5/16,.svc,.svc.cluster,.svc.cluster.local,.cluster.local",
+		},
+		{
+			name:       "Dual-stack CIDRs",
+			options:    `{"cluster-cidr":"10.42.0.0/16,2001:cafe:42::/56","service-cidr":"10.43.0.0/16,2001:cafe:43::/112"}`,
+			interfaces: []string{"eth*"},
+			want:       "10.42.0.0/16,2001:cafe:42::/56,10.43.0.0/16,2001:cafe:43::/112,192.168.1.10/24,fd00::10/64,10.0.0.5/16,.svc,.svc.cluster,.svc.cluster.local,.cluster.local",
+		},
+		{
+			name:       "Excluded interfaces and custom domain",
+			options:    `{"cluster-cidr":"10.42.0.0/16","service-cidr":"10.43.0.0/16","cluster-domain":"k8s.example"}`,
+			interfaces: []string{"!cni*", "!eth1"},
+			want:       "10.42.0.0/16,10.43.0.0/16,192.168.1.10/24,fd00::10/64,.svc,.svc.cluster,.svc.cluster.local,.svc.k8s.example,.k8s.example",
+		},
+		{
+			name:       "Duplicates",
+			options:    `{"cluster-cidr":"10.42.0.0/16,10.42.0.0/16","service-cidr":"10.43.0.0/16"}`,
+			interfaces: []string{"eth1", "eth1"},
+			want:       "10.42.0.0/16,10.43.0.0/16,10.0.0.5/16,.svc,.svc.cluster,.svc.cluster.local,.cluster.local",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getDefaultNoProxy([]byte(tt.options), tt.interfaces, nil)
+			if err != nil {
+				t.Fatalf("getDefaultNoProxy() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("getDefaultNoProxy() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getDefaultNoProxyErrors(t *testing.T) {
+	fakeInterfaces(t, map[string][]string{"eth0": {"192.168.1.10/24"}})
+
+	if _, err := getDefaultNoProxy([]byte(`null`), []string{"eth["}, nil); err == nil {
+		t.Errorf("getDefaultNoProxy() expected an error for an invalid interface pattern")
+	}
+	if _, err := getDefaultNoProxy([]byte(`{"cluster-cidr":{"ipv4":"10.42.0.0/16"}}`), nil, nil); err == nil {
+		t.Errorf("getDefaultNoProxy() expected an error for an invalid cluster-cidr")
+	}
+}
+
+func Test_getDefaultNoProxyNodeAddresses(t *testing.T) {
+	fakeInterfaces(t, map[string][]string{"eth0": {"192.168.1.10/24"}})
+
+	got, err := getDefaultNoProxy([]byte(`null`), []string{"eth0"}, []string{"10.0.0.5", "fd00::5/64"})
+	if err != nil {
+		t.Fatalf("getDefaultNoProxy() error = %v", err)
+	}
+	if want := "10.42.0.0/16,10.43.0.0/16,10.0.0.5,fd00::5/64,.svc,.svc.cluster,.svc.cluster.local,.cluster.local"; got != want {
+		t.Errorf("getDefaultNoProxy() = %s, want %s", got, want)
+	}
+
+	got, err = getDefaultNoProxy([]byte(`null`), nil, []string{})
+	if err != nil {
+		t.Fatalf("getDefaultNoProxy() error = %v", err)
+	}
+	if want := "10.42.0.0/16,10.43.0.0/16,.svc,.svc.cluster,.svc.cluster.local,.cluster.local"; got != want {
+		t.Errorf("getDefaultNoProxy() without node addresses = %s, want %s", got, want)
+	}
+}
+
+func Test_proxyEnv(t *testing.T) {
+	fakeInterfaces(t, map[string][]string{"eth0": {"192.168.1.10/24"}})
+
+	tests := []struct {
+		name    string
+		options string
+		env     map[string]string
+		want    string
+	}{
+		{
+			name:    "No proxy",
+			options: `null`,
+			env:     map[string]string{"FOO": "bar"},
+			want:    "",
+		},
+		{
+			name:    "Only NO_PROXY",
+			options: `null`,
+			env:     map[string]string{"NO_PROXY": "localhost"},
+			want: `NO_PROXY=localhost
+no_proxy=localhost
+CONTAINERD_NO_PROXY=localhost`,
+		},
+		{
+			name:    "Upper case",
+			options: `{"cluster-cidr":"10.42.0.0/16","service-cidr":"10.43.0.0/16"}`,
+			env: map[string]string{
+				"HTTP_PROXY": "http://proxy:3128",
+				"NO_PROXY":   "localhost, 10.42.0.0/16,.example.com",
+			},
+			want: `HTTP_PROXY=http://proxy:3128
+http_proxy=http://proxy:3128
+CONTAINERD_HTTP_PROXY=http://proxy:3128
+NO_PROXY=10.42.0.0/16,10.43.0.0/16,192.168.1.10/24,.svc,.svc.cluster,.svc.cluster.local,.cluster.local,localhost,.example.com
+no_proxy=10.42.0.0/16,10.43.0.0/16,192.168.1.10/24,.svc,.svc.cluster,.svc.cluster.local,.cluster.local,localhost,.example.com
+CONTAINERD_NO_PROXY=10.42.0.0/16,10.43.0.0/16,192.168.1.10/24,.svc,.svc.cluster,.svc.cluster.local,.cluster.local,localhost,.example.com`,
+		},
+		{
+			name:    "Lower case without CIDRs",
+			options: `{"node-name":"node"}`,
+			env: map[string]string{
+				"https_proxy": "http://proxy:3128",
+				"HTTPS_PROXY": "http://upper:3128",
+				"no_proxy":    ".example.com",
+			},
+			want: `HTTPS_PROXY=http://upper:3128
+https_proxy=http://upper:3128
+CONTAINERD_HTTPS_PROXY=http://upper:3128
+NO_PROXY=10.42.0.0/16,10.43.0.0/16,192.168.1.10/24,.svc,.svc.cluster,.svc.cluster.local,.cluster.local,.example.com
+no_proxy=10.42.0.0/16,10.43.0.0/16,192.168.1.10/24,.svc,.svc.cluster,.svc.cluster.local,.cluster.local,.example.com
+CONTAINERD_NO_PROXY=10.42.0.0/16,10.43.0.0/16,192.168.1.10/24,.svc,.svc.cluster,.svc.cluster.local,.cluster.local,.example.com`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := proxyEnv([]byte(tt.options), tt.env, nil, nil)
+			if err != nil {
+				t.Fatalf("proxyEnv() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("proxyEnv() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yip "github.com/mudler/yip/pkg/schema"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+const (
+	registriesFile     = "/etc/rancher/k3s/registries.yaml"
+	registriesCertsDir = "/etc/rancher/k3s/registries.d"
+)
+
+// getRegistriesFiles renders registries.yaml, writing inline certificates to files under registriesCertsDir.
+func getRegistriesFiles(registries *api.Registries) ([]yip.File, error) {
+	if registries == nil {
+		return nil, nil
+	}
+
+	var files []yip.File
+	out := api.Registries{
+		Mirrors: registries.Mirrors,
+		Configs: make(map[string]api.RegistryConfig, len(registries.Configs)),
+	}
+
+	hosts := make([]string, 0, len(registries.Configs))
+	for host := range registries.Configs {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		cfg := registries.Configs[host]
+		if cfg.TLS != nil {
+			tls, tlsFiles, err := getRegistryTLSFiles(host, *cfg.TLS)
+			if err != nil {
+				return nil, err
+			}
+			cfg.TLS = &tls
+			files = append(files, tlsFiles...)
+		}
+		out.Configs[host] = cfg
+	}
+
+	for name, mirror := range registries.Mirrors {
+		for _, endpoint := range mirror.Endpoints {
+			if strings.TrimSpace(endpoint) == "" {
+				return nil, fmt.Errorf("registries: mirror %q has an empty endpoint", name)
+			}
+		}
+	}
+
+	content, err := yaml.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal registries: %w", err)
+	}
+
+	return append([]yip.File{{
+		Path:        registriesFile,
+		Permissions: 0600,
+		Content:     string(content),
+	}}, files...), nil
+}
+
+func getRegistryTLSFiles(host string, tls api.RegistryTLS) (api.RegistryTLS, []yip.File, error) {
+	var files []yip.File
+	dir := filepath.Join(registriesCertsDir, sanitizeHost(host))
+
+	inline := []struct {
+		name    string
+		content string
+		path    *string
+		file    string
+	}{
+		{"ca", tls.CA, &tls.CAFile, "ca.crt"},
+		{"cert", tls.Cert, &tls.CertFile, "client.crt"},
+		{"key", tls.Key, &tls.KeyFile, "client.key"},
+	}
+	for _, i := range inline {
+		if i.content == "" {
+			continue
+		}
+		if *i.path != "" {
+			return tls, nil, fmt.Errorf("registries: %s of %q is set both inline and as a file", i.name, host)
+		}
+		*i.path = filepath.Join(dir, i.file)
+		files = append(files, yip.File{
+			Path:        *i.path,
+			Permissions: 0600,
+			Content:     i.content,
+		})
+	}
+
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		return tls, nil, fmt.Errorf("registries: %q needs both a client certificate and key", host)
+	}
+
+	tls.CA, tls.Cert, tls.Key = "", "", ""
+	return tls, files, nil
+}
+
+// sanitizeHost turns a registry host such as registry.local:5000 into a directory name.
+func sanitizeHost(host string) string {
+	return strings.NewReplacer(":", "_", "/", "_").Replace(host)
+}
@@ -0,0 +1,181 @@
+package provider
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	yip "github.com/mudler/yip/pkg/schema"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+const (
+	serviceDropInName = "90-provider-k3s.conf"
+	openRCConfDir     = "/etc/conf.d"
+)
+
+// getServiceStages renders the service section into a systemd drop-in under /run, so it is recreated every boot like
+// the runtime enablement. On OpenRC, which only reads the persistent conf.d file of the service, the settings also go
+// to a file under /run that the conf.d file sources when it exists, so the user's conf.d settings are kept and dropping
+// the section takes effect on the next boot. Each stage only runs on its init system.
+func getServiceStages(service *api.Service, systemName string) ([]yip.Stage, error) {
+	if service == nil {
+		return nil, nil
+	}
+	if err := api.ValidateService(service); err != nil {
+		return nil, err
+	}
+
+	var stages []yip.Stage
+	if dropIn := renderSystemdDropIn(service); dropIn != "" {
+		stages = append(stages, yip.Stage{
+			Name: constants.InstallSystemdDropIns,
+			If:   "[ -x /bin/systemctl ]",
+			Files: []yip.File{
+				{
+					Path:        filepath.Join(constants.RunSystemdSystemDir, systemName+".service.d", serviceDropInName),
+					Permissions: 0644,
+					Content:     dropIn,
+				},
+			},
+		})
+	}
+	if conf := renderOpenRCConf(service); conf != "" {
+		settings := filepath.Join(constants.RunDir, systemName+".openrc.conf")
+		confD := filepath.Join(openRCConfDir, systemName)
+		source := fmt.Sprintf("if [ -f %[1]s ]; then . %[1]s; fi", settings)
+		stages = append(stages, yip.Stage{
+			Name: constants.InstallOpenRCConf,
+			If:   "[ -x /sbin/openrc-run ]",
+			Files: []yip.File{
+				{
+					Path:        settings,
+					Permissions: 0644,
+					Content:     conf,
+				},
+			},
+			Commands: []string{
+				fmt.Sprintf("grep -qxF %[1]s %[2]s 2>/dev/null || echo %[1]s >> %[2]s", shellQuote(source), confD),
+			},
+		})
+	}
+	return stages, nil
+}
+
+func renderSystemdDropIn(service *api.Service) string {
+	var unit, svc []string
+	for _, d := range []struct {
+		key   string
+		units []string
+	}{
+		{"After", service.After},
+		{"Wants", service.Wants},
+		{"Requires", service.Requires},
+	} {
+		if len(d.units) > 0 {
+			unit = append(unit, fmt.Sprintf("%s=%s", d.key, strings.Join(d.units, " ")))
+		}
+	}
+	for _, d := range []struct {
+		key, value string
+	}{
+		{"LimitNOFILE", service.LimitNOFILE},
+		{"LimitNPROC", service.LimitNPROC},
+		{"TimeoutStartSec", service.TimeoutStartSec},
+		{"Restart", service.Restart},
+		{"RestartSec", service.RestartSec},
+		{"Delegate", service.Delegate},
+	} {
+		if d.value != "" {
+			svc = append(svc, fmt.Sprintf("%s=%s", d.key, d.value))
+		}
+	}
+
+	var sections []string
+	if len(unit) > 0 {
+		sections = append(sections, "[Unit]\n"+strings.Join(unit, "\n")+"\n")
+	}
+	if len(svc) > 0 {
+		sections = append(sections, "[Service]\n"+strings.Join(svc, "\n")+"\n")
+	}
+	return strings.Join(sections, "\n")
+}
+
+// renderOpenRCConf maps the settings OpenRC has an equivalent for. TimeoutStartSec, Restart and Delegate have none
+// and only apply to systemd.
+func renderOpenRCConf(service *api.Service) string {
+	var lines, ulimits []string
+	if service.LimitNOFILE != "" {
+		ulimits = append(ulimits, "-n "+openRCLimit(service.LimitNOFILE))
+	}
+	if service.LimitNPROC != "" {
+		ulimits = append(ulimits, "-u "+openRCLimit(service.LimitNPROC))
+	}
+	if len(ulimits) > 0 {
+		lines = append(lines, fmt.Sprintf("rc_ulimit=%q", strings.Join(ulimits, " ")))
+	}
+
+	for _, d := range []struct {
+		key   string
+		units []string
+	}{
+		{"rc_after", service.After},
+		{"rc_want", service.Wants},
+		{"rc_need", service.Requires},
+	} {
+		if names := openRCServices(d.units); len(names) > 0 {
+			lines = append(lines, fmt.Sprintf("%s=%q", d.key, strings.Join(names, " ")))
+		}
+	}
+
+	if delay, ok := openRCSeconds(service.RestartSec); ok {
+		lines = append(lines, fmt.Sprintf("respawn_delay=%d", delay))
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func openRCLimit(v string) string {
+	if v == "infinity" {
+		return "unlimited"
+	}
+	return v
+}
+
+// openRCServices turns systemd service units into OpenRC service names. Targets and other unit types have no OpenRC
+// counterpart and are skipped.
+func openRCServices(units []string) []string {
+	var out []string
+	for _, unit := range units {
+		ext := filepath.Ext(unit)
+		switch ext {
+		case ".service":
+			out = append(out, strings.TrimSuffix(unit, ext))
+		case "":
+			out = append(out, unit)
+		}
+	}
+	return out
+}
+
+// openRCSeconds parses a systemd time span such as 5, 5s or 1min30s into whole seconds.
+func openRCSeconds(v string) (int, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(v); err == nil {
+		return n, true
+	}
+	d, err := time.ParseDuration(strings.ReplaceAll(strings.ReplaceAll(v, "min", "m"), " ", ""))
+	if err != nil {
+		return 0, false
+	}
+	return int(d.Seconds()), true
+}
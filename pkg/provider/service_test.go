@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+func Test_serviceStages(t *testing.T) {
+	service := &api.Service{
+		LimitNOFILE:     "infinity",
+		LimitNPROC:      "4096",
+		TimeoutStartSec: "0",
+		Restart:         "always",
+		RestartSec:      "1min30s",
+		Delegate:        "yes",
+		After:           []string{"network-online.target", "iscsid.service"},
+		Wants:           []string{"network-online.target"},
+		Requires:        []string{"containerd"},
+	}
+
+	stages, err := getServiceStages(service, agentSystemName)
+	if err != nil {
+		t.Fatalf("getServiceStages() error = %v", err)
+	}
+	if len(stages) != 2 {
+		t.Fatalf("expected 2 stages, got %+v", stages)
+	}
+
+	systemd := stages[0]
+	if systemd.Name != constants.InstallSystemdDropIns || len(systemd.Files) != 1 {
+		t.Fatalf("unexpected systemd stage %+v", systemd)
+	}
+	if path := systemd.Files[0].Path; path != "/run/systemd/system/k3s-agent.service.d/90-provider-k3s.conf" {
+		t.Errorf("drop-in path = %s", path)
+	}
+	wantDropIn := `[Unit]
+After=network-online.target iscsid.service
+Wants=network-online.target
+Requires=containerd
+
+[Service]
+LimitNOFILE=infinity
+LimitNPROC=4096
+TimeoutStartSec=0
+Restart=always
+RestartSec=1min30s
+Delegate=yes
+`
+	if got := systemd.Files[0].Content; got != wantDropIn {
+		t.Errorf("drop-in =\n%s\nwant\n%s", got, wantDropIn)
+	}
+
+	openrc := stages[1]
+	if openrc.Name != constants.InstallOpenRCConf || len(openrc.Files) != 1 {
+		t.Fatalf("unexpected OpenRC stage %+v", openrc)
+	}
+	if path := openrc.Files[0].Path; path != "/run/provider-k3s/k3s-agent.openrc.conf" {
+		t.Errorf("settings path = %s", path)
+	}
+	wantCommands := []string{
+		`grep -qxF 'if [ -f /run/provider-k3s/k3s-agent.openrc.conf ]; then . /run/provider-k3s/k3s-agent.openrc.conf; fi' /etc/conf.d/k3s-agent 2>/dev/null || echo 'if [ -f /run/provider-k3s/k3s-agent.openrc.conf ]; then . /run/provider-k3s/k3s-agent.openrc.conf; fi' >> /etc/conf.d/k3s-agent`,
+	}
+	if !reflect.DeepEqual(openrc.Commands, wantCommands) {
+		t.Errorf("OpenRC commands = %q, want %q", openrc.Commands, wantCommands)
+	}
+	wantConf := `rc_ulimit="-n unlimited -u 4096"
+rc_after="iscsid"
+rc_need="containerd"
+respawn_delay=90
+`
+	if got := openrc.Files[0].Content; got != wantConf {
+		t.Errorf("settings =\n%s\nwant\n%s", got, wantConf)
+	}
+}
+
+func Test_serviceStagesSystemdOnly(t *testing.T) {
+	stages, err := getServiceStages(&api.Service{Delegate: "yes", After: []string{"network-online.target"}}, serverSystemName)
+	if err != nil {
+		t.Fatalf("getServiceStages() error = %v", err)
+	}
+	if len(stages) != 1 || stages[0].Name != constants.InstallSystemdDropIns {
+		t.Errorf("expected only the systemd stage, got %+v", stages)
+	}
+
+	if stages, _ := getServiceStages(nil, serverSystemName); len(stages) != 0 {
+		t.Errorf("expected no stages without a service section, got %+v", stages)
+	}
+}
+
+func Test_serviceStagesErrors(t *testing.T) {
+	for name, service := range map[string]*api.Service{
+		"Invalid restart":  {Restart: "sometimes"},
+		"Multi-line value": {LimitNOFILE: "1\nExecStart=/bin/sh"},
+		"Invalid unit":     {After: []string{"a b"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := getServiceStages(service, serverSystemName); err == nil {
+				t.Errorf("getServiceStages() expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	yip "github.com/mudler/yip/pkg/schema"
+
+	"github.com/kairos-io/provider-k3s/api"
+	"github.com/kairos-io/provider-k3s/pkg/constants"
+)
+
+const swapKubeletConfigFile = "50-provider-k3s-swap.conf"
+
+// getSwapStage turns swap off for the disable policy. The other policies leave /etc/fstab untouched, which also
+// suits images where it is read-only.
+func getSwapStage(swap *api.Swap) *yip.Stage {
+	if swap.SwapPolicy() != api.SwapDisable {
+		return nil
+	}
+	return &yip.Stage{
+		Name: "disable disk swap",
+		Commands: []string{
+			"sed -i '/ swap / s/^\\(.*\\)$/#\\1/g' /etc/fstab",
+			"swapoff -a",
+		},
+	}
+}
+
+// getSwapKubeletArgs lets the kubelet start on a node with swap for the allow policy.
+func getSwapKubeletArgs(swap *api.Swap) []string {
+	if swap.SwapPolicy() != api.SwapAllow {
+		return nil
+	}
+	return []string{"fail-swap-on=false"}
+}
+
+func getSwapKubeletConfigPath(cluster clusterplugin.Cluster) string {
+	return filepath.Join(getDataDir(cluster), constants.K3sKubeletConfigDir, swapKubeletConfigFile)
+}
+
+// getSwapFiles sets the swap behavior of the allow policy. It has no kubelet flag, so it goes into a drop-in of the
+// kubelet config directory k3s manages. The other policies render nothing, and the config stage deletes a drop-in left
+// by an earlier allow policy.
+func getSwapFiles(cluster clusterplugin.Cluster, swap *api.Swap) []yip.File {
+	if swap.SwapPolicy() != api.SwapAllow {
+		return nil
+	}
+	behavior := swap.SwapBehavior
+	if behavior == "" {
+		behavior = api.LimitedSwap
+	}
+	return []yip.File{
+		{
+			Path:        getSwapKubeletConfigPath(cluster),
+			Permissions: 0644,
+			Content: fmt.Sprintf(`apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+failSwapOn: false
+memorySwap:
+  swapBehavior: %s
+`, behavior),
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+func Test_swapPolicies(t *testing.T) {
+	tests := []struct {
+		name        string
+		swap        *api.Swap
+		stage       bool
+		kubeletArgs []string
+		behavior    string
+	}{
+		{name: "Default", stage: true},
+		{name: "Disable", swap: &api.Swap{Policy: api.SwapDisable}, stage: true},
+		{name: "Ignore", swap: &api.Swap{Policy: api.SwapIgnore}},
+		{name: "Allow", swap: &api.Swap{Policy: api.SwapAllow}, kubeletArgs: []string{"fail-swap-on=false"}, behavior: "LimitedSwap"},
+		{name: "Allow without swap", swap: &api.Swap{Policy: api.SwapAllow, SwapBehavior: api.NoSwap}, kubeletArgs: []string{"fail-swap-on=false"}, behavior: "NoSwap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := api.ValidateSwap(tt.swap); err != nil {
+				t.Fatalf("ValidateSwap() error = %v", err)
+			}
+			if stage := getSwapStage(tt.swap); (stage != nil) != tt.stage {
+				t.Errorf("getSwapStage() = %+v, want a stage: %t", stage, tt.stage)
+			}
+			if args := getSwapKubeletArgs(tt.swap); strings.Join(args, ",") != strings.Join(tt.kubeletArgs, ",") {
+				t.Errorf("getSwapKubeletArgs() = %v, want %v", args, tt.kubeletArgs)
+			}
+
+			files := getSwapFiles(clusterplugin.Cluster{Role: "worker"}, tt.swap)
+			if tt.behavior == "" {
+				if len(files) != 0 {
+					t.Errorf("getSwapFiles() = %+v, want none", files)
+				}
+				return
+			}
+			if len(files) != 1 || files[0].Path != "/var/lib/rancher/k3s/agent/etc/kubelet.conf.d/50-provider-k3s-swap.conf" {
+				t.Fatalf("getSwapFiles() = %+v", files)
+			}
+			if !strings.Contains(files[0].Content, "swapBehavior: "+tt.behavior) {
+				t.Errorf("kubelet drop-in does not set %s:\n%s", tt.behavior, files[0].Content)
+			}
+		})
+	}
+}
+
+func Test_swapDropInRemoved(t *testing.T) {
+	remove := "rm -f '/var/lib/rancher/k3s/agent/etc/kubelet.conf.d/50-provider-k3s-swap.conf'"
+	for _, tt := range []struct {
+		options string
+		removed bool
+	}{
+		{options: "swap:\n  policy: allow", removed: false},
+		{options: "swap:\n  policy: ignore", removed: true},
+		{options: "swap:\n  policy: disable", removed: true},
+		{options: "", removed: true},
+	} {
+		cluster := clusterplugin.Cluster{ClusterToken: "token", Role: clusterplugin.RoleInit, Options: tt.options}
+		commands := configFilesCommands(t, cluster)
+		if got := slices.Contains(commands, remove); got != tt.removed {
+			t.Errorf("options %q: drop-in removed = %t, want %t: %q", tt.options, got, tt.removed, commands)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kairos-io/provider-k3s/api"
+)
+
+type Severity string
+
+const (
+	// SeverityError marks settings k3s will never see or cannot parse.
+	SeverityError Severity = "error"
+	// SeverityWarning marks settings that are dropped for this role but may be meant for another one.
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a problem found in the config section of a cluster.
+type Issue struct {
+	Key      string
+	Severity Severity
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+}
+
+// maxSuggestionDistance is the largest edit distance for which a known key is offered as a suggestion.
+const maxSuggestionDistance = 3
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Validate checks the cluster config section against the k3s config of the node role. Keys are reported when no
+// role knows them, when only the other role knows them, and when their value does not fit the config field.
+func Validate(cluster clusterplugin.Cluster) ([]Issue, error) {
+	var options map[string]interface{}
+	if err := yaml.Unmarshal([]byte(cluster.Options), &options); err != nil {
+		return nil, fmt.Errorf("failed to un-marshal cluster options: %w", err)
+	}
+
+	roleFields, otherFields, otherRole := fieldsFor(cluster.Role)
+
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var issues []Issue
+	for _, key := range keys {
+		value := options[key]
+
+		if api.IsProviderConfigKey(key) {
+			if cfg, err := api.ParseProviderConfig(map[string]interface{}{key: value}); err != nil {
+				issues = append(issues, Issue{Key: key, Severity: SeverityError, Message: fmt.Sprintf("section %q: %s", key, err)})
+			} else if err := cfg.Validate(); err != nil {
+				issues = append(issues, Issue{Key: key, Severity: SeverityError, Message: fmt.Sprintf("section %q: %s", key, err)})
+			} else if cluster.Role == clusterplugin.RoleWorker && slices.Contains(api.ServerOnlyProviderConfigKeys, key) {
+				issues = append(issues, Issue{
+					Key:      key,
+					Severity: SeverityWarning,
+					Message:  fmt.Sprintf("section %q is only valid for server nodes and is ignored on worker nodes", key),
+				})
+			}
+			continue
+		}
+
+		if t, ok := roleFields[key]; ok {
+			if expected, ok := checkType(key, t, value); !ok {
+				issues = append(issues, Issue{
+					Key:      key,
+					Severity: SeverityError,
+					Message:  fmt.Sprintf("key %q expects %s, got %s", key, expected, describe(value)),
+				})
+			}
+			continue
+		}
+
+		if _, ok := otherFields[key]; ok {
+			issues = append(issues, Issue{
+				Key:      key,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("key %q is only valid for %s nodes and is ignored on %s nodes", key, otherRole, roleName(cluster.Role)),
+			})
+			continue
+		}
+
+		message := fmt.Sprintf("unknown key %q", key)
+		if suggestion := suggest(key, roleFields, otherFields, fieldMap(api.ProviderConfigFields())); suggestion != "" {
+			message = fmt.Sprintf("%s, did you mean %q?", message, suggestion)
+		}
+		issues = append(issues, Issue{Key: key, Severity: SeverityError, Message: message})
+	}
+
+	return issues, nil
+}
+
+// HasErrors reports whether any issue has SeverityError.
+func HasErrors(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldsFor(role clusterplugin.Role) (map[string]reflect.Type, map[string]reflect.Type, string) {
+	server := fieldMap(api.ServerFields())
+	agent := fieldMap(api.AgentFields())
+	if role == clusterplugin.RoleWorker {
+		return agent, server, "server"
+	}
+	return server, agent, clusterplugin.RoleWorker
+}
+
+func fieldMap(fields []api.Field) map[string]reflect.Type {
+	out := make(map[string]reflect.Type, len(fields))
+	for _, f := range fields {
+		out[f.Key] = f.Type
+	}
+	return out
+}
+
+func roleName(role clusterplugin.Role) string {
+	if role == "" {
+		return clusterplugin.RoleInit
+	}
+	return string(role)
+}
+
+// checkType reports whether value of key can be decoded into a field of type t, and describes t otherwise.
+func checkType(key string, t reflect.Type, value interface{}) (string, bool) {
+	if value == nil {
+		return "", true
+	}
+	if t == durationType {
+		switch v := value.(type) {
+		case int:
+			return "", true
+		case string:
+			_, err := time.ParseDuration(v)
+			return "a duration such as 30s", err == nil
+		}
+		return "a duration such as 30s", false
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "a string", isScalar(value)
+	case reflect.Bool:
+		_, ok := value.(bool)
+		return "a boolean", ok
+	case reflect.Int, reflect.Int64:
+		_, ok := value.(int)
+		return "an integer", ok
+	case reflect.Slice:
+		if list, ok := value.([]interface{}); ok {
+			for _, item := range list {
+				if !isScalar(item) {
+					return "a list of strings", false
+				}
+			}
+			return "", true
+		}
+		// A string is split into a list when it holds commas or the key is a known list key, see decodeOptions.
+		s, ok := value.(string)
+		return "a list of strings", ok && (strings.Contains(s, ",") || slices.Contains(api.StringListKeys, key))
+	}
+	return "", true
+}
+
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case string, int, float64, bool:
+		return true
+	}
+	return false
+}
+
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case bool:
+		return fmt.Sprintf("boolean %t", v)
+	case int, float64:
+		return fmt.Sprintf("number %v", v)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "a map"
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// suggest returns the known key closest to key, preferring keys of the node role on ties.
+func suggest(key string, fieldSets ...map[string]reflect.Type) string {
+	best, bestDistance := "", maxSuggestionDistance+1
+	for _, fields := range fieldSets {
+		candidates := make([]string, 0, len(fields))
+		for k := range fields {
+			candidates = append(candidates, k)
+		}
+		sort.Strings(candidates)
+		for _, candidate := range candidates {
+			if d := distance(key, candidate); d < bestDistance {
+				best, bestDistance = candidate, d
+			}
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein edit distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kairos-io/kairos-sdk/clusterplugin"
+)
+
+func Test_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster clusterplugin.Cluster
+		want    []Issue
+	}{
+		{
+			name:    "Empty input",
+			cluster: clusterplugin.Cluster{},
+		},
+		{
+			name: "Valid server config",
+			cluster: clusterplugin.Cluster{
+				Role: "init",
+				Options: `tls-san: a,b
+cluster-cidr: 10.42.0.0/16
+https-listen-port: 6443
+etcd-s3-timeout: 5m
+node-label:
+  - a=b`,
+			},
+		},
+		{
+			name: "Misspelled key",
+			cluster: clusterplugin.Cluster{
+				Role:    "worker",
+				Options: `node-labels: a=b`,
+			},
+			want: []Issue{
+				{Key: "node-labels", Severity: SeverityError, Message: `unknown key "node-labels", did you mean "node-label"?`},
+			},
+		},
+		{
+			name: "Unknown key without suggestion",
+			cluster: clusterplugin.Cluster{
+				Role:    "init",
+				Options: `completely-unrelated: true`,
+			},
+			want: []Issue{
+				{Key: "completely-unrelated", Severity: SeverityError, Message: `unknown key "completely-unrelated"`},
+			},
+		},
+		{
+			name: "Key of the other role",
+			cluster: clusterplugin.Cluster{
+				Role: "controlplane",
+				Options: `disable-apiserver-lb: true
+`,
+			},
+			want: []Issue{
+				{Key: "disable-apiserver-lb", Severity: SeverityWarning, Message: `key "disable-apiserver-lb" is only valid for worker nodes and is ignored on controlplane nodes`},
+			},
+		},
+		{
+			name: "Provider section",
+			cluster: clusterplugin.Cluster{
+				Role: "worker",
+				Options: `registries:
+  mirrors:
+    docker.io:
+      endpoints: [https://mirror]`,
+			},
+			want: []Issue{
+				{Key: "registries", Severity: SeverityError, Message: "section \"registries\": failed to un-marshal provider sections of cluster options: yaml: unmarshal errors:\n  line 4: field endpoints not found in type api.RegistryMirror"},
+			},
+		},
+		{
+			name: "Env section",
+			cluster: clusterplugin.Cluster{
+				Role: "init",
+				Options: `env:
+  K3S_DEBUG: "true"
+  1BAD: x`,
+			},
+			want: []Issue{
+				{Key: "env", Severity: SeverityError, Message: `section "env": env: invalid variable name "1BAD"`},
+			},
+		},
+		{
+			name: "Type mismatches",
+			cluster: clusterplugin.Cluster{
+				Role: "init",
+				Options: `https-listen-port: abc
+enable-pprof: "yes"
+etcd-s3-timeout: soon
+node-taint:
+  key: value`,
+			},
+			want: []Issue{
+				{Key: "enable-pprof", Severity: SeverityError, Message: `key "enable-pprof" expects a boolean, got string "yes"`},
+				{Key: "etcd-s3-timeout", Severity: SeverityError, Message: `key "etcd-s3-timeout" expects a duration such as 30s, got string "soon"`},
+				{Key: "https-listen-port", Severity: SeverityError, Message: `key "https-listen-port" expects an integer, got string "abc"`},
+				{Key: "node-taint", Severity: SeverityError, Message: `key "node-taint" expects a list of strings, got a map`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Validate(tt.cluster)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ValidateInvalidYAML(t *testing.T) {
+	if _, err := Validate(clusterplugin.Cluster{Options: "node-name: [unterminated"}); err == nil {
+		t.Errorf("Validate() expected an error")
+	}
+}
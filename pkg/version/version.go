@@ -1,3 +1,6 @@
 package version
 
 var Version string
+
+// BuildDate is the RFC 3339 time the binary was built at, set with -ldflags.
+var BuildDate string